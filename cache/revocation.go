@@ -0,0 +1,82 @@
+// cache/revocation.go
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RevocationStore adalah interface untuk menyimpan ID sesi yang sudah dicabut.
+// Setiap entri hanya perlu disimpan sampai waktu kedaluwarsanya, karena setelah itu
+// token milik sesi tersebut sudah tidak berlaku dengan sendirinya.
+// Implementasi bersama (misalnya Redis) dapat dipasang dengan memenuhi interface ini.
+type RevocationStore interface {
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// NewRevocationStore membuat RevocationStore sesuai backend yang dikonfigurasi
+func NewRevocationStore(backend string) (RevocationStore, error) {
+	switch backend {
+	case "", "memory":
+		return NewMemoryRevocationStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported revocation store backend %q", backend)
+	}
+}
+
+// memorySweepInterval adalah jeda minimal antar pembersihan entri kedaluwarsa
+const memorySweepInterval = time.Minute
+
+// MemoryRevocationStore adalah implementasi RevocationStore di memori proses.
+// Pada deployment dengan beberapa instance, isinya perlu disinkronkan secara berkala dari database.
+type MemoryRevocationStore struct {
+	mu        sync.RWMutex
+	entries   map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryRevocationStore membuat instance baru dari MemoryRevocationStore
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		entries:   make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Revoke menandai id sebagai dicabut sampai expiresAt
+func (s *MemoryRevocationStore) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.entries[id]; !ok || expiresAt.After(current) {
+		s.entries[id] = expiresAt
+	}
+
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		for key, exp := range s.entries {
+			if !exp.After(now) {
+				delete(s.entries, key)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	return nil
+}
+
+// IsRevoked memeriksa apakah id sudah dicabut dan entrinya belum kedaluwarsa
+func (s *MemoryRevocationStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	s.mu.RLock()
+	expiresAt, ok := s.entries[id]
+	s.mu.RUnlock()
+
+	return ok && expiresAt.After(time.Now()), nil
+}
@@ -0,0 +1,31 @@
+// config/branding.go
+package config
+
+// BrandingConfig menyimpan tampilan halaman HTML yang dibuka dari link email (verifikasi, reset password, dll.)
+type BrandingConfig struct {
+	// ProductName adalah nama aplikasi yang tampil di judul dan footer halaman
+	ProductName string
+	// LogoURL adalah URL gambar logo di atas halaman, kosongkan untuk tanpa logo
+	LogoURL string
+	// PrimaryColor, SuccessColor dan ErrorColor adalah warna judul dan tombol (format CSS, misalnya "#007bff")
+	PrimaryColor string
+	SuccessColor string
+	ErrorColor   string
+	// LoginURL adalah tujuan tombol login setelah proses berhasil
+	LoginURL string
+	// DefaultLanguage adalah bahasa halaman jika Accept-Language tidak cocok dengan bahasa yang tersedia ("en" atau "id")
+	DefaultLanguage string
+}
+
+// LoadBrandingConfig memuat konfigurasi tampilan dari variabel lingkungan
+func LoadBrandingConfig() BrandingConfig {
+	return BrandingConfig{
+		ProductName:     getEnv("BRAND_PRODUCT_NAME", "Aurauran"),
+		LogoURL:         getEnv("BRAND_LOGO_URL", ""),
+		PrimaryColor:    getEnv("BRAND_PRIMARY_COLOR", "#007bff"),
+		SuccessColor:    getEnv("BRAND_SUCCESS_COLOR", "#28a745"),
+		ErrorColor:      getEnv("BRAND_ERROR_COLOR", "#721c24"),
+		LoginURL:        getEnv("BRAND_LOGIN_URL", "/auth/login"),
+		DefaultLanguage: getEnv("BRAND_DEFAULT_LANGUAGE", "en"),
+	}
+}
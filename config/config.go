@@ -3,6 +3,10 @@ package config
 
 import (
 	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +19,11 @@ type Config struct {
     Email    EmailConfig
     Logger   LoggerConfig
     Storage  StorageConfig // Tambahkan StorageConfig di sini
+    Security SecurityConfig
+    OAuth    OAuthConfig
+    Password PasswordPolicyConfig
+    Cookie   CookieConfig
+    Branding BrandingConfig
 }
 
 var AppConfig *Config
@@ -33,5 +42,95 @@ func LoadConfig() {
         Email:    LoadEmailConfig(),
         Logger:   LoadLoggerConfig(),
         Storage:  LoadStorageConfig(), // Inisialisasi StorageConfig di sini
+        Security: LoadSecurityConfig(),
+        OAuth:    LoadOAuthConfig(),
+        Password: LoadPasswordPolicyConfig(),
+        Cookie:   LoadCookieConfig(),
+        Branding: LoadBrandingConfig(),
     }
 }
+
+// parseDurationEnv membaca durasi (misalnya "15m" atau "720h") dari variabel lingkungan,
+// dan mengembalikan nilai fallback jika kosong atau tidak valid.
+// Format lama tetap diterima: angka tanpa satuan dianggap detik ("3600") dan akhiran "d" berarti hari ("7d").
+func parseDurationEnv(key string, fallback time.Duration) time.Duration {
+    value := strings.TrimSpace(os.Getenv(key))
+    if value == "" {
+        return fallback
+    }
+    d, err := parseDuration(value)
+    if err != nil || d <= 0 {
+        log.Printf("Invalid duration for %s (%q), using default %s", key, value, fallback)
+        return fallback
+    }
+    return d
+}
+
+// parseDuration mengurai durasi Go, jumlah detik, atau jumlah hari dengan akhiran "d"
+func parseDuration(value string) (time.Duration, error) {
+    if seconds, err := strconv.Atoi(value); err == nil {
+        return time.Duration(seconds) * time.Second, nil
+    }
+    if days, ok := strings.CutSuffix(value, "d"); ok {
+        n, err := strconv.Atoi(days)
+        if err != nil {
+            return 0, err
+        }
+        return time.Duration(n) * 24 * time.Hour, nil
+    }
+    return time.ParseDuration(value)
+}
+
+// parseIntEnv membaca bilangan bulat positif dari variabel lingkungan,
+// dan mengembalikan nilai fallback jika kosong atau tidak valid
+func parseIntEnv(key string, fallback int) int {
+    value := os.Getenv(key)
+    if value == "" {
+        return fallback
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil || n <= 0 {
+        log.Printf("Invalid integer for %s (%q), using default %d", key, value, fallback)
+        return fallback
+    }
+    return n
+}
+
+// getEnv membaca variabel lingkungan dan mengembalikan nilai fallback jika kosong
+func getEnv(key, fallback string) string {
+    if value := os.Getenv(key); value != "" {
+        return value
+    }
+    return fallback
+}
+
+// parseListEnv membaca daftar nilai dipisah koma dari variabel lingkungan.
+// Jika variabel tidak diset, nilai fallback dikembalikan; jika diset kosong, hasilnya daftar kosong.
+func parseListEnv(key string, fallback []string) []string {
+    value, ok := os.LookupEnv(key)
+    if !ok {
+        return fallback
+    }
+    list := []string{}
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            list = append(list, item)
+        }
+    }
+    return list
+}
+
+// parseBoolEnv membaca nilai boolean ("true", "1", "false", "0") dari variabel lingkungan,
+// dan mengembalikan nilai fallback jika kosong atau tidak valid
+func parseBoolEnv(key string, fallback bool) bool {
+    value := os.Getenv(key)
+    if value == "" {
+        return fallback
+    }
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+        log.Printf("Invalid boolean for %s (%q), using default %t", key, value, fallback)
+        return fallback
+    }
+    return b
+}
@@ -0,0 +1,51 @@
+// config/cookie.go
+package config
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CookieConfig menyimpan konfigurasi sesi berbasis cookie untuk aplikasi browser
+type CookieConfig struct {
+	// Enabled mengaktifkan login berbasis cookie: token disimpan di cookie HttpOnly,
+	// dan request yang mengubah data wajib membawa token CSRF
+	Enabled bool
+	// AccessTokenName adalah nama cookie yang menyimpan access token
+	AccessTokenName string
+	// RefreshTokenName adalah nama cookie yang menyimpan refresh token, hanya dikirim ke route /auth
+	RefreshTokenName string
+	// CSRFName adalah nama cookie yang menyimpan token CSRF (double-submit)
+	CSRFName string
+	// Domain adalah domain cookie, kosong untuk host API saja
+	Domain string
+	// Secure membatasi cookie hanya dikirim lewat HTTPS
+	Secure bool
+	// SameSite adalah atribut SameSite cookie ("lax", "strict" atau "none")
+	SameSite http.SameSite
+}
+
+// LoadCookieConfig memuat konfigurasi cookie dari variabel lingkungan
+func LoadCookieConfig() CookieConfig {
+	return CookieConfig{
+		Enabled:          parseBoolEnv("AUTH_COOKIE_ENABLED", false),
+		AccessTokenName:  getEnv("AUTH_COOKIE_NAME", "aurauran_session"),
+		RefreshTokenName: getEnv("AUTH_REFRESH_COOKIE_NAME", "aurauran_refresh"),
+		CSRFName:         getEnv("CSRF_COOKIE_NAME", "aurauran_csrf"),
+		Domain:           getEnv("AUTH_COOKIE_DOMAIN", ""),
+		Secure:           parseBoolEnv("AUTH_COOKIE_SECURE", true),
+		SameSite:         parseSameSite(getEnv("AUTH_COOKIE_SAMESITE", "lax")),
+	}
+}
+
+// parseSameSite mengubah nilai SameSite dari konfigurasi, nilai yang tidak dikenal dianggap "lax"
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
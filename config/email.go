@@ -11,6 +11,10 @@ type EmailConfig struct {
     Sender            string
     VerifyURL         string
     ResetPasswordURL  string
+    UnlockAccountURL  string
+    EmailChangeURL    string
+    MagicLinkURL      string
+    TeamInvitationURL string
 }
 
 // LoadEmailConfig memuat konfigurasi email dari variabel lingkungan
@@ -23,5 +27,9 @@ func LoadEmailConfig() EmailConfig {
         Sender:           os.Getenv("SMTP_SENDER"),
         VerifyURL:        os.Getenv("EMAIL_VERIFY_URL"),
         ResetPasswordURL: os.Getenv("EMAIL_RESET_PASSWORD_URL"),
+        UnlockAccountURL: os.Getenv("EMAIL_UNLOCK_ACCOUNT_URL"),
+        EmailChangeURL:   os.Getenv("EMAIL_CHANGE_URL"),
+        MagicLinkURL:     os.Getenv("EMAIL_MAGIC_LINK_URL"),
+        TeamInvitationURL: os.Getenv("EMAIL_TEAM_INVITATION_URL"),
     }
 }
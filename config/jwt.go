@@ -1,13 +1,22 @@
 // config/jwt.go
 package config
 
-import "os"
+import (
+    "os"
+    "time"
+)
 
 // JWTConfig menyimpan konfigurasi JWT
 type JWTConfig struct {
     Secret          string
     ExpiresIn       string
     RefreshExpiresIn string
+    AccessTokenTTL  time.Duration
+    RefreshTokenTTL time.Duration
+    // Algorithm adalah algoritma kunci penandatangan baru: RS256 atau EdDSA.
+    // Secret tidak lagi dipakai untuk menandatangani token, hanya untuk mengenkripsi kunci privat di database.
+    Algorithm           string
+    KeyRotationInterval time.Duration
 }
 
 // LoadJWTConfig memuat konfigurasi JWT dari variabel lingkungan
@@ -16,5 +25,9 @@ func LoadJWTConfig() JWTConfig {
         Secret:          os.Getenv("JWT_SECRET"),
         ExpiresIn:       os.Getenv("JWT_EXPIRES_IN"),
         RefreshExpiresIn: os.Getenv("JWT_REFRESH_EXPIRES_IN"),
+        AccessTokenTTL:  parseDurationEnv("JWT_EXPIRES_IN", 15*time.Minute),
+        RefreshTokenTTL: parseDurationEnv("JWT_REFRESH_EXPIRES_IN", 30*24*time.Hour),
+        Algorithm:           getEnv("JWT_ALGORITHM", "RS256"),
+        KeyRotationInterval: parseDurationEnv("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
     }
 }
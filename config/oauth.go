@@ -0,0 +1,55 @@
+// config/oauth.go
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// OAuthProviderConfig menyimpan konfigurasi satu provider OpenID Connect
+type OAuthProviderConfig struct {
+	// Name adalah nama provider yang dipakai pada URL, misalnya "google" untuk /auth/oauth/google/start
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OAuthConfig menyimpan konfigurasi login OAuth2 / OpenID Connect
+type OAuthConfig struct {
+	Providers map[string]OAuthProviderConfig
+	// StateTTL adalah batas waktu antara /start dan /callback
+	StateTTL time.Duration
+}
+
+// LoadOAuthConfig memuat konfigurasi OAuth dari variabel lingkungan.
+// OAUTH_PROVIDERS berisi daftar nama provider dipisah koma, lalu setiap provider dikonfigurasi
+// dengan OAUTH_<NAMA>_ISSUER, OAUTH_<NAMA>_CLIENT_ID, OAUTH_<NAMA>_CLIENT_SECRET,
+// OAUTH_<NAMA>_REDIRECT_URL dan (opsional) OAUTH_<NAMA>_SCOPES.
+func LoadOAuthConfig() OAuthConfig {
+	providers := make(map[string]OAuthProviderConfig)
+	for _, name := range strings.Split(os.Getenv("OAUTH_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers[name] = OAuthProviderConfig{
+			Name:         name,
+			Issuer:       strings.TrimRight(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+	}
+
+	return OAuthConfig{
+		Providers: providers,
+		StateTTL:  parseDurationEnv("OAUTH_STATE_TTL", 10*time.Minute),
+	}
+}
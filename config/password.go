@@ -0,0 +1,38 @@
+// config/password.go
+package config
+
+import "os"
+
+// PasswordPolicyConfig menyimpan aturan password yang berlaku saat registrasi, reset dan perubahan password
+type PasswordPolicyConfig struct {
+	// MinLength dan MaxLength adalah panjang password yang diizinkan (bcrypt hanya memakai 72 byte pertama)
+	MinLength int
+	MaxLength int
+
+	// RequireUpper, RequireLower, RequireDigit dan RequireSymbol mewajibkan jenis karakter tertentu
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// HistorySize adalah jumlah password terakhir yang tidak boleh dipakai ulang
+	HistorySize int
+
+	// BreachedListPath adalah file berisi hash SHA-1 (hex) password yang pernah bocor, satu per baris.
+	// Format "HASH:JUMLAH" seperti daftar Pwned Passwords juga diterima. Kosongkan untuk mematikan pengecekan.
+	BreachedListPath string
+}
+
+// LoadPasswordPolicyConfig memuat aturan password dari variabel lingkungan
+func LoadPasswordPolicyConfig() PasswordPolicyConfig {
+	return PasswordPolicyConfig{
+		MinLength:        parseIntEnv("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:        parseIntEnv("PASSWORD_MAX_LENGTH", 72),
+		RequireUpper:     parseBoolEnv("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:     parseBoolEnv("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:     parseBoolEnv("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:    parseBoolEnv("PASSWORD_REQUIRE_SYMBOL", false),
+		HistorySize:      parseIntEnv("PASSWORD_HISTORY_SIZE", 5),
+		BreachedListPath: os.Getenv("PASSWORD_BREACHED_LIST"),
+	}
+}
@@ -0,0 +1,83 @@
+// config/security.go
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// SecurityConfig menyimpan konfigurasi keamanan akun
+type SecurityConfig struct {
+	// BootstrapAdminCode adalah kode undangan admin yang dibuat saat startup jika belum ada admin sama sekali
+	BootstrapAdminCode string
+
+	// LoginMaxAttempts adalah jumlah kegagalan login berturut-turut sebelum akun dikunci sementara
+	LoginMaxAttempts int
+	// LoginIPMaxAttempts adalah jumlah kegagalan login dari satu IP dalam LoginAttemptWindow sebelum IP tersebut diblokir
+	LoginIPMaxAttempts int
+	// LoginAttemptWindow adalah rentang waktu penghitungan kegagalan login per IP
+	LoginAttemptWindow time.Duration
+	// LoginLockoutDuration adalah lama akun dikunci setelah mencapai LoginMaxAttempts
+	LoginLockoutDuration time.Duration
+	// LoginBackoffBase adalah jeda awal setelah kegagalan login, berlipat dua setiap kegagalan berikutnya
+	LoginBackoffBase time.Duration
+
+	// TOTPIssuer adalah nama aplikasi yang tampil di aplikasi authenticator
+	TOTPIssuer string
+	// MFAChallengeTTL adalah masa berlaku token tantangan MFA yang dikembalikan oleh login
+	MFAChallengeTTL time.Duration
+
+	// VerificationResendCooldown adalah jeda minimal antar pengiriman ulang email verifikasi ke alamat yang sama
+	VerificationResendCooldown time.Duration
+
+	// MagicLinkRoles adalah daftar role yang boleh login lewat magic link (dipisah koma, kosong untuk mematikan fitur)
+	MagicLinkRoles []string
+	// MagicLinkTTL adalah masa berlaku link login yang dikirim lewat email
+	MagicLinkTTL time.Duration
+
+	// TeamInvitationTTL adalah masa berlaku undangan tim yang dikirim lewat email
+	TeamInvitationTTL time.Duration
+
+	// AccountDeletionGracePeriod adalah jeda antara permintaan hapus akun dan penghapusan data pribadinya.
+	// Login selama jeda ini membatalkan penghapusan.
+	AccountDeletionGracePeriod time.Duration
+
+	// RevocationStore adalah backend penyimpanan sesi yang dicabut ("memory")
+	RevocationStore string
+	// RevocationSyncInterval adalah jeda sinkronisasi sesi yang dicabut dari database ke RevocationStore,
+	// agar pencabutan dari instance lain ikut terbaca
+	RevocationSyncInterval time.Duration
+	// UserCacheTTL adalah lama data user disimpan di cache AuthMiddleware. Perubahan dari instance ini langsung
+	// menghapus cache; perubahan dari instance lain terbaca paling lambat setelah jeda ini
+	UserCacheTTL time.Duration
+	// CleanupInterval adalah jeda antar penghapusan token, sesi dan data login yang sudah kedaluwarsa
+	CleanupInterval time.Duration
+	// LoginAttemptRetention adalah lama catatan percobaan login disimpan
+	LoginAttemptRetention time.Duration
+}
+
+// LoadSecurityConfig memuat konfigurasi keamanan dari variabel lingkungan
+func LoadSecurityConfig() SecurityConfig {
+	return SecurityConfig{
+		BootstrapAdminCode:   os.Getenv("ADMIN_BOOTSTRAP_CODE"),
+		LoginMaxAttempts:     parseIntEnv("LOGIN_MAX_ATTEMPTS", 5),
+		LoginIPMaxAttempts:   parseIntEnv("LOGIN_IP_MAX_ATTEMPTS", 20),
+		LoginAttemptWindow:   parseDurationEnv("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		LoginLockoutDuration: parseDurationEnv("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+		LoginBackoffBase:     parseDurationEnv("LOGIN_BACKOFF_BASE", time.Second),
+		TOTPIssuer:           getEnv("TOTP_ISSUER", "Aurauran"),
+		MFAChallengeTTL:      parseDurationEnv("MFA_CHALLENGE_TTL", 5*time.Minute),
+
+		VerificationResendCooldown: parseDurationEnv("VERIFICATION_RESEND_COOLDOWN", 2*time.Minute),
+		MagicLinkRoles:             parseListEnv("MAGIC_LINK_ENABLED_ROLES", []string{"manager", "member"}),
+		MagicLinkTTL:               parseDurationEnv("MAGIC_LINK_TTL", 15*time.Minute),
+		TeamInvitationTTL:          parseDurationEnv("TEAM_INVITATION_TTL", 7*24*time.Hour),
+		AccountDeletionGracePeriod: parseDurationEnv("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+
+		RevocationStore:        getEnv("REVOCATION_STORE", "memory"),
+		RevocationSyncInterval: parseDurationEnv("REVOCATION_SYNC_INTERVAL", 30*time.Second),
+		UserCacheTTL:           parseDurationEnv("USER_CACHE_TTL", 30*time.Second),
+		CleanupInterval:        parseDurationEnv("TOKEN_CLEANUP_INTERVAL", time.Hour),
+		LoginAttemptRetention:  parseDurationEnv("LOGIN_ATTEMPT_RETENTION", 30*24*time.Hour),
+	}
+}
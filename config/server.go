@@ -7,12 +7,15 @@ import "os"
 type ServerConfig struct {
     Port string
     Env  string
+    // AllowedOrigins adalah origin yang boleh memanggil API dari browser (CORS), termasuk dengan cookie
+    AllowedOrigins []string
 }
 
 // LoadServerConfig memuat konfigurasi server dari variabel lingkungan
 func LoadServerConfig() ServerConfig {
     return ServerConfig{
-        Port: os.Getenv("PORT"),
-        Env:  os.Getenv("ENV"),
+        Port:           os.Getenv("PORT"),
+        Env:            os.Getenv("ENV"),
+        AllowedOrigins: parseListEnv("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173", "https://zacht.tech"}),
     }
 }
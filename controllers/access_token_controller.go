@@ -0,0 +1,162 @@
+// controllers/access_token_controller.go
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+)
+
+// CreatePersonalAccessTokenRequest represents the request structure for creating a personal access token
+type CreatePersonalAccessTokenRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ProjectID *uint      `json:"project_id" binding:"omitempty"`
+	ExpiresAt *time.Time `json:"expires_at" binding:"omitempty"`
+}
+
+// CreatePersonalAccessToken handles creating a personal access token for the current user.
+// The plain token is only returned in this response.
+func CreatePersonalAccessToken(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req CreatePersonalAccessTokenRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Validate and de-duplicate the scopes
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool)
+	for _, scope := range req.Scopes {
+		if !models.IsValidPersonalAccessTokenScope(scope) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid scope: "+scope)
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	if req.ProjectID != nil {
+		hasAccess, err := userCanAccessProject(user, *req.ProjectID)
+		if err != nil {
+			utils.Logger.Errorf("Failed to check project access: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create token")
+			return
+		}
+		if !hasAccess {
+			utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
+			return
+		}
+	}
+
+	secret, err := utils.GenerateRandomToken(20)
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate personal access token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create token")
+		return
+	}
+	plainToken := models.PersonalAccessTokenPrefix + secret
+
+	pat := models.PersonalAccessToken{
+		UserID:      user.ID,
+		Name:        req.Name,
+		TokenPrefix: plainToken[:len(models.PersonalAccessTokenPrefix)+5],
+		TokenHash:   utils.HashToken(plainToken),
+		Scopes:      scopes,
+		ProjectID:   req.ProjectID,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if err := models.DB.Create(&pat).Error; err != nil {
+		utils.Logger.Errorf("Failed to create personal access token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create token")
+		return
+	}
+
+	utils.Logger.Infof("Personal access token created: TokenID %d by UserID %d", pat.ID, user.ID)
+
+	utils.CreatedResponse(c, gin.H{
+		"token":        plainToken,
+		"access_token": pat,
+		"message":      "Copy the token now, it will not be shown again.",
+	})
+}
+
+// ListPersonalAccessTokens handles listing the personal access tokens of the current user
+func ListPersonalAccessTokens(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var tokens []models.PersonalAccessToken
+	if err := models.DB.Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Order("created_at desc").
+		Find(&tokens).Error; err != nil {
+		utils.Logger.Errorf("Failed to retrieve personal access tokens: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve tokens")
+		return
+	}
+
+	utils.SuccessResponse(c, tokens)
+}
+
+// RevokePersonalAccessToken handles revoking a personal access token of the current user
+func RevokePersonalAccessToken(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("token_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+
+	result := models.DB.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", uint(tokenID), user.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		utils.Logger.Errorf("Failed to revoke personal access token: %v", result.Error)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke token")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Token not found")
+		return
+	}
+
+	utils.Logger.Infof("Personal access token revoked: TokenID %d by UserID %d", tokenID, user.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "Token revoked successfully"})
+}
+
+// userCanAccessProject checks whether the permission matrix lets the user view the project
+func userCanAccessProject(user models.User, projectID uint) (bool, error) {
+	permissions, err := models.ProjectPermissions(user, projectID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return permissions.Has(models.PermissionProjectView), nil
+}
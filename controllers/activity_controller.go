@@ -51,18 +51,6 @@ func CreateActivity(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectIDUint))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create activity")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var req CreateActivityRequest
 	// Bind JSON request to struct
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -112,21 +100,6 @@ func CreateActivity(c *gin.Context) {
 
 // ListActivities handles retrieving all activities within a project
 func ListActivities(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-
 	// Retrieve project_id dari URL parameters dan konversi ke uint
 	projectIDParam := c.Param("project_id")
 	projectIDUint, err := strconv.ParseUint(projectIDParam, 10, 64)
@@ -135,18 +108,6 @@ func ListActivities(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectIDUint))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve activities")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var activities []models.Activity
 	// Retrieve all activities for the project, termasuk User yang membuat setiap activity
 	if err := models.DB.Where("project_id = ?", uint(projectIDUint)).
@@ -177,21 +138,6 @@ func ListActivities(c *gin.Context) {
 
 // GetActivity handles retrieving a single activity by ID within a project
 func GetActivity(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-
 	// Retrieve project_id dan activity_id dari URL parameters dan konversi ke uint
 	projectIDParam := c.Param("project_id")
 	projectIDUint, err := strconv.ParseUint(projectIDParam, 10, 64)
@@ -207,18 +153,6 @@ func GetActivity(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectIDUint))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve activity")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var activity models.Activity
 	// Retrieve the activity from the database, termasuk User yang membuatnya
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(activityIDUint), uint(projectIDUint)).
@@ -292,18 +226,6 @@ func UpdateActivity(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectIDUint))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update activity")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var activity models.Activity
 	// Retrieve the activity dari database
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(activityIDUint), uint(projectIDUint)).First(&activity).Error; err != nil {
@@ -381,18 +303,6 @@ func DeleteActivity(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectIDUint))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete activity")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var activity models.Activity
 	// Retrieve the activity dari database
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(activityIDUint), uint(projectIDUint)).First(&activity).Error; err != nil {
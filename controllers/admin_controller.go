@@ -0,0 +1,304 @@
+// controllers/admin_controller.go
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+)
+
+// UnlockUser handles clearing the login lockout of a user (admin only)
+func UnlockUser(c *gin.Context) {
+	admin, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, uint(userID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to retrieve user: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to unlock user")
+		return
+	}
+
+	if err := clearLoginLockout(models.DB, user.ID); err != nil {
+		utils.Logger.Errorf("Failed to unlock user: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to unlock user")
+		return
+	}
+
+	// Unlock links that were emailed are no longer needed
+	if err := models.DB.Where("user_id = ? AND type = ?", user.ID, models.TokenTypeAccountUnlock).Delete(&models.Token{}).Error; err != nil {
+		utils.Logger.Errorf("Failed to delete unlock tokens: %v", err)
+	}
+
+	utils.Logger.Infof("User unlocked: UserID %d by admin UserID %d", user.ID, admin.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "User unlocked successfully"})
+}
+
+// UpdateUserRoleRequest represents the request structure for changing the role of a user
+type UpdateUserRoleRequest struct {
+	Role models.Role `json:"role" binding:"required,oneof=admin manager member"`
+}
+
+// ListUsers handles listing users with optional search and filters (admin only).
+// Query parameters: q (matches username or email), role, status, page and page_size.
+func ListUsers(c *gin.Context) {
+	pagination, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := models.DB.Model(&models.User{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := likePattern(strings.ToLower(q), false)
+		query = query.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	page, err := paginate(query, &pagination)
+	if err != nil {
+		utils.Logger.Errorf("Failed to count users: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve users")
+		return
+	}
+
+	var users []models.User
+	if err := page.Order("id").Find(&users).Error; err != nil {
+		utils.Logger.Errorf("Failed to retrieve users: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve users")
+		return
+	}
+
+	accounts := make([]userAccount, 0, len(users))
+	for _, user := range users {
+		accounts = append(accounts, newUserAccount(user))
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"users":      accounts,
+		"pagination": pagination,
+	})
+}
+
+// GetUser handles retrieving a single user (admin only)
+func GetUser(c *gin.Context) {
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, newUserAccount(user))
+}
+
+// UpdateUserRole handles changing the role of a user (admin only)
+func UpdateUserRole(c *gin.Context) {
+	admin, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	// An admin demoting themselves could leave nobody able to manage users
+	if user.ID == admin.ID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "You cannot change your own role")
+		return
+	}
+
+	if err := models.DB.Model(&user).Update("role", req.Role).Error; err != nil {
+		utils.Logger.Errorf("Failed to update user role: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update role")
+		return
+	}
+	user.Role = req.Role
+
+	utils.Logger.Infof("User role changed: UserID %d to %s by admin UserID %d", user.ID, req.Role, admin.ID)
+
+	utils.SuccessResponse(c, newUserAccount(user))
+}
+
+// DisableUser handles disabling an account and signing it out everywhere (admin only)
+func DisableUser(c *gin.Context) {
+	setUserStatus(c, models.UserStatusDisabled)
+}
+
+// EnableUser handles re-enabling a disabled account (admin only)
+func EnableUser(c *gin.Context) {
+	setUserStatus(c, models.UserStatusActive)
+}
+
+// setUserStatus changes the status of the user in the route, revoking all access when disabling
+func setUserStatus(c *gin.Context, status models.UserStatus) {
+	admin, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if user.ID == admin.ID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "You cannot change the status of your own account")
+		return
+	}
+
+	if user.Status == models.UserStatusDeleted {
+		utils.ErrorResponse(c, http.StatusConflict, "The account has been deleted")
+		return
+	}
+
+	if err := models.DB.Model(&user).Update("status", status).Error; err != nil {
+		utils.Logger.Errorf("Failed to update user status: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update user status")
+		return
+	}
+	user.Status = status
+
+	if status == models.UserStatusDisabled {
+		if err := revokeAllAccess(user.ID); err != nil {
+			utils.Logger.Errorf("Failed to revoke access of disabled user: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke user sessions")
+			return
+		}
+	}
+
+	utils.Logger.Infof("User status changed: UserID %d to %s by admin UserID %d", user.ID, status, admin.ID)
+
+	utils.SuccessResponse(c, newUserAccount(user))
+}
+
+// ForceLogoutUser handles revoking every session and personal access token of a user (admin only)
+func ForceLogoutUser(c *gin.Context) {
+	admin, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := revokeAllAccess(user.ID); err != nil {
+		utils.Logger.Errorf("Failed to force logout: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke user sessions")
+		return
+	}
+
+	utils.Logger.Infof("User logged out everywhere: UserID %d by admin UserID %d", user.ID, admin.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "User logged out from all sessions"})
+}
+
+// ForcePasswordReset handles requiring a user to choose a new password (admin only).
+// The user is signed out and a reset link is emailed; login is refused until the password is reset.
+func ForcePasswordReset(c *gin.Context) {
+	admin, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	var resetToken string
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password_reset_required", true).Error; err != nil {
+			return err
+		}
+
+		var err error
+		resetToken, err = issueToken(tx, user.ID, models.TokenTypePasswordReset, time.Now().Add(24*time.Hour))
+		return err
+	})
+	if err != nil {
+		utils.Logger.Errorf("Failed to force password reset: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to force password reset")
+		return
+	}
+
+	if err := models.RevokeUserSessions(user.ID); err != nil {
+		utils.Logger.Errorf("Failed to revoke sessions for password reset: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke user sessions")
+		return
+	}
+
+	emailService := utils.NewEmailService()
+	if err := emailService.SendResetPasswordEmail(user.Email, resetToken); err != nil {
+		utils.Logger.Errorf("Failed to send password reset email: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send password reset email")
+		return
+	}
+
+	utils.Logger.Infof("Password reset forced: UserID %d by admin UserID %d", user.ID, admin.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "Password reset required, a reset link has been sent to the user"})
+}
+
+// revokeAllAccess signs a user out of every session and revokes their personal access tokens
+func revokeAllAccess(userID uint) error {
+	if err := models.RevokeUserSessions(userID); err != nil {
+		return err
+	}
+	return models.RevokeUserAccessTokens(userID)
+}
+
+// adminTargetUser loads the user named by the user_id route parameter.
+// It writes the error response itself, so callers only need to return when ok is false.
+func adminTargetUser(c *gin.Context) (models.User, bool) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return models.User{}, false
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, uint(userID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+			return models.User{}, false
+		}
+		utils.Logger.Errorf("Failed to retrieve user: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve user")
+		return models.User{}, false
+	}
+
+	return user, true
+}
@@ -2,24 +2,29 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
 	"github.com/mfuadfakhruzzaki/backendaurauran/models"
 	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"github.com/mfuadfakhruzzaki/backendaurauran/views"
 )
 
 // RegisterRequest represents the request structure for user registration
 type RegisterRequest struct {
 	Username       string `json:"username" binding:"required"`
 	Email          string `json:"email" binding:"required,email"`
-	Password       string `json:"password" binding:"required,min=6"`
+	Password       string `json:"password" binding:"required"`
 	InvitationCode string `json:"invitation_code"`
 }
 
@@ -29,6 +34,12 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// RefreshTokenRequest represents the request structure for rotating a refresh token.
+// Browsers using cookie sessions send the refresh token as a cookie and may leave the body empty.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // RequestPasswordResetRequest represents the request structure for requesting a password reset
 type RequestPasswordResetRequest struct {
 	Email string `json:"email" binding:"required,email"`
@@ -37,14 +48,24 @@ type RequestPasswordResetRequest struct {
 // ResetPasswordRequest represents the request structure for resetting the password via API
 type ResetPasswordRequest struct {
 	Token       string `json:"token" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 // ResetPasswordFormRequest represents the form data for resetting the password via HTML form
 type ResetPasswordFormRequest struct {
 	Token           string `form:"token" binding:"required"`
-	NewPassword     string `form:"new_password" binding:"required,min=6"`
-	ConfirmPassword string `form:"confirm_password" binding:"required,min=6"`
+	NewPassword     string `form:"new_password" binding:"required"`
+	ConfirmPassword string `form:"confirm_password" binding:"required"`
+	CSRFToken       string `form:"csrf_token"`
+}
+
+// resetPasswordPage is the data of the reset password form and its error page
+type resetPasswordPage struct {
+	Token     string
+	CSRFToken string
+	// Reasons are the message keys explaining why a submitted form was rejected
+	Reasons []string
+	Policy  config.PasswordPolicyConfig
 }
 
 // Register handles user registration
@@ -56,14 +77,6 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Determine user role based on invitation code
-	role := models.RoleMember // Default role
-	if req.InvitationCode == "GWEHADMIN" {
-		role = models.RoleAdmin
-	} else if req.InvitationCode == "GWEHMANAGER" {
-		role = models.RoleManager
-	}
-
 	// Check if user with the same email or username already exists
 	var existingUser models.User
 	if err := models.DB.Where("email = ? OR username = ?", req.Email, req.Username).First(&existingUser).Error; err == nil {
@@ -79,45 +92,56 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	if !checkPasswordPolicy(c, req.Password, req.Username, req.Email, 0) {
+		return
+	}
+
 	// Create new user
 	user := models.User{
 		Username: req.Username,
 		Email:    req.Email,
-		Password: req.Password, // Password will be hashed by GORM hook
-		Role:     role,
-	}
+		Password: req.Password,      // Password will be hashed by GORM hook
+		Role:     models.RoleMember, // Default role
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		// Redeem the invitation code, which determines the role and optional team/project scope
+		var invitation *models.Invitation
+		if req.InvitationCode != "" {
+			var err error
+			invitation, err = redeemInvitation(tx, req.InvitationCode)
+			if err != nil {
+				return err
+			}
+			user.Role = invitation.Role
+		}
 
-	if err := models.DB.Create(&user).Error; err != nil {
-		utils.Logger.Errorf("Failed to create user: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register user")
-		return
-	}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
 
-	// Generate email verification token
-	verifyToken, err := utils.GenerateRandomToken(32)
-	if err != nil {
-		utils.Logger.Errorf("Failed to generate verification token: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register user")
-		return
-	}
+		// The BeforeCreate hook has replaced the password with its hash
+		if err := recordPasswordHistory(tx, user.ID, user.Password); err != nil {
+			return err
+		}
 
-	emailToken := models.Token{
-		UserID:    user.ID,
-		Token:     verifyToken,
-		Type:      models.TokenTypeEmailVerify,
-		ExpiresAt: time.Now().Add(24 * time.Hour), // Token valid for 24 hours
+		if invitation != nil {
+			return joinInvitationScope(tx, invitation, &user)
+		}
+		return nil
+	})
+	if err == errInvitationUnusable {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired invitation code")
+		return
 	}
-
-	if err := models.DB.Create(&emailToken).Error; err != nil {
-		utils.Logger.Errorf("Failed to save verification token: %v", err)
+	if err != nil {
+		utils.Logger.Errorf("Failed to create user: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register user")
 		return
 	}
 
 	// Send verification email
-	emailService := utils.NewEmailService()
-	verifyURL := fmt.Sprint(verifyToken)
-	if err := emailService.SendVerificationEmail(user.Email, verifyURL); err != nil {
+	if err := sendVerificationEmail(user); err != nil {
 		utils.Logger.Errorf("Failed to send verification email: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send verification email")
 		return
@@ -140,10 +164,16 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Reject clients that have failed too often from the same IP
+	if !checkIPLoginThrottle(c) {
+		return
+	}
+
 	var user models.User
 	// Find user by email
 	if err := models.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
+			recordLoginAttempt(c, req.Email, nil, false)
 			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid email or password")
 			return
 		}
@@ -152,8 +182,18 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Reject locked accounts and attempts made inside the back-off window
+	if !checkAccountLoginThrottle(c, user) {
+		recordLoginAttempt(c, req.Email, &user.ID, false)
+		return
+	}
+
 	// Check if password matches
 	if !user.ComparePassword(req.Password) {
+		if registerFailedLogin(c, &user) {
+			utils.ErrorResponse(c, http.StatusLocked, "Account is temporarily locked due to too many failed login attempts")
+			return
+		}
 		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
@@ -164,62 +204,159 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID, user.Role)
-	if err != nil {
-		utils.Logger.Errorf("Failed to generate JWT: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login")
+	finishLogin(c, user)
+}
+
+// RefreshToken exchanges a valid refresh token for a new access token and rotates the refresh token.
+// Presenting a refresh token that was already used or revoked revokes its entire family.
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	// Bind JSON request to struct, an empty body is allowed when the refresh token comes from a cookie
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken = utils.RefreshTokenCookie(c)
+		// Cookies are sent by the browser on its own, so cookie requests must prove they come from our client
+		if req.RefreshToken != "" && !utils.ValidCSRFToken(c, c.GetHeader(utils.CSRFHeaderName)) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Invalid CSRF token")
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Refresh token is required")
+		return
+	}
+
+	var stored models.RefreshToken
+	if err := models.DB.Where("token_hash = ?", utils.HashToken(req.RefreshToken)).First(&stored).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+		utils.Logger.Errorf("Failed to find refresh token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to refresh token")
 		return
 	}
 
-	utils.Logger.Infof("User logged in successfully: %s", user.Email)
+	// A used or revoked token coming back means it has leaked: kill the whole family
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		revokeReusedFamily(stored)
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Refresh token has been revoked")
+		return
+	}
 
-	// Send success response with token
-	utils.SuccessResponse(c, gin.H{
-		"token": token,
+	if stored.ExpiresAt.Before(time.Now()) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Refresh token has expired")
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, stored.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to find user for refresh token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	if !checkAccountStatus(c, user) {
+		return
+	}
+
+	var tokens gin.H
+	reused := false
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		// Mark the presented token as used; zero affected rows means a concurrent exchange won the race
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", stored.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			return nil
+		}
+
+		var next *models.RefreshToken
+		var err error
+		tokens, next, err = issueTokenPair(c, tx, user, stored.FamilyID)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).Where("id = ?", stored.ID).Update("replaced_by", next.ID).Error
 	})
+	if err != nil {
+		utils.Logger.Errorf("Failed to rotate refresh token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+	if reused {
+		revokeReusedFamily(stored)
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Refresh token has been revoked")
+		return
+	}
+
+	utils.Logger.Infof("Refresh token rotated for user ID: %d", user.ID)
+
+	sendTokens(c, tokens, false)
 }
 
-// Logout handles user logout by blacklisting the JWT token
+// Logout handles user logout by revoking the session of the access token.
+// Browser sessions are logged out with the session cookie, whose cookies are cleared as well.
 func Logout(c *gin.Context) {
-	// Get token from Authorization header
+	var tokenStr string
+
+	// Get token from Authorization header, or from the session cookie
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Authorization header required")
-		return
-	}
+		tokenStr = utils.AccessTokenCookie(c)
+		refreshToken := utils.RefreshTokenCookie(c)
+		if tokenStr == "" && refreshToken == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Authorization header required")
+			return
+		}
+		if !utils.ValidCSRFToken(c, c.GetHeader(utils.CSRFHeaderName)) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Invalid CSRF token")
+			return
+		}
+		utils.ClearAuthCookies(c)
 
-	// Extract token
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid authorization header format")
-		return
+		// The access token cookie expires before the refresh token cookie; find the session through the latter
+		if tokenStr == "" {
+			logoutRefreshTokenSession(c, refreshToken)
+			return
+		}
+	} else {
+		// Extract token
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid authorization header format")
+			return
+		}
+		tokenStr = parts[1]
 	}
-	tokenStr := parts[1]
 
 	// Parse JWT token to get claims
 	claims, err := utils.ParseJWT(tokenStr)
-	if err != nil {
+	if err != nil || claims.ID == "" {
 		utils.Logger.Errorf("Failed to parse JWT token: %v", err)
 		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid token")
 		return
 	}
 
-	// Add token to blacklist with UserID from claims
-	blacklistToken := models.Token{
-		UserID:    claims.UserID,
-		Token:     tokenStr,
-		Type:      models.TokenTypeJWTBlacklist,
-		ExpiresAt: time.Unix(claims.ExpiresAt, 0), // Ensure correct expiration
-	}
-
-	if err := models.DB.Create(&blacklistToken).Error; err != nil {
-		utils.Logger.Errorf("Failed to blacklist token: %v", err)
+	// Revoke the session together with its refresh tokens
+	if err := models.RevokeSession(claims.UserID, claims.ID); err != nil && err != gorm.ErrRecordNotFound {
+		utils.Logger.Errorf("Failed to revoke session: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to logout")
 		return
 	}
 
-	utils.Logger.Infof("Token blacklisted successfully for user ID: %d", claims.UserID)
+	utils.Logger.Infof("Session %s revoked for user ID: %d", claims.ID, claims.UserID)
 
 	// Send success response
 	utils.SuccessResponse(c, gin.H{
@@ -227,61 +364,137 @@ func Logout(c *gin.Context) {
 	})
 }
 
-// VerifyEmail handles email verification
-func VerifyEmail(c *gin.Context) {
-	tokenStr := c.Query("token")
-	if tokenStr == "" {
-		// Render failure page with message
-		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(failureHTML))
-		return
-	}
-
-	var token models.Token
-	if err := models.DB.Where("token = ? AND type = ?", tokenStr, models.TokenTypeEmailVerify).First(&token).Error; err != nil {
+// logoutRefreshTokenSession revokes the session a refresh token belongs to
+func logoutRefreshTokenSession(c *gin.Context, refreshToken string) {
+	var stored models.RefreshToken
+	if err := models.DB.Where("token_hash = ?", utils.HashToken(refreshToken)).First(&stored).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			// Render failure page with message
-			c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(failureHTML))
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid token")
 			return
 		}
-		utils.Logger.Errorf("Failed to verify email token: %v", err)
-		// Render generic failure page
-		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(failureHTML))
+		utils.Logger.Errorf("Failed to find refresh token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to logout")
 		return
 	}
 
-	if token.ExpiresAt.Before(time.Now()) {
-		// Render failure page with expired token message
-		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(failureHTML))
+	if err := models.RevokeSession(stored.UserID, stored.FamilyID); err != nil && err != gorm.ErrRecordNotFound {
+		utils.Logger.Errorf("Failed to revoke session: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to logout")
 		return
 	}
 
-	var user models.User
-	if err := models.DB.First(&user, token.UserID).Error; err != nil {
-		utils.Logger.Errorf("User not found for email verification: %v", err)
-		// Render generic failure page
-		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(failureHTML))
-		return
+	utils.Logger.Infof("Session %s revoked for user ID: %d", stored.FamilyID, stored.UserID)
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Successfully logged out",
+	})
+}
+
+// issueTokenPair creates an access token and a refresh token for the user.
+// An empty sessionID starts a new session (a new login); otherwise the session is
+// extended and the refresh token joins its family.
+func issueTokenPair(c *gin.Context, tx *gorm.DB, user models.User, sessionID string) (gin.H, *models.RefreshToken, error) {
+	now := time.Now()
+	expiresAt := now.Add(config.AppConfig.JWT.RefreshTokenTTL)
+
+	if sessionID == "" {
+		session := models.Session{
+			ID:         uuid.New().String(),
+			UserID:     user.ID,
+			UserAgent:  truncate(c.Request.UserAgent(), 512),
+			IPAddress:  c.ClientIP(),
+			LastSeenAt: now,
+			ExpiresAt:  expiresAt,
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to create session: %w", err)
+		}
+		sessionID = session.ID
+	} else {
+		if err := tx.Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+			"ip_address":   c.ClientIP(),
+			"last_seen_at": now,
+			"expires_at":   expiresAt,
+		}).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to extend session: %w", err)
+		}
 	}
 
-	user.IsEmailVerified = true
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
 
-	if err := models.DB.Save(&user).Error; err != nil {
-		utils.Logger.Errorf("Failed to update user email verification: %v", err)
-		// Render generic failure page
-		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(failureHTML))
+	stored := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: expiresAt,
+	}
+	if err := tx.Create(&stored).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	accessToken, err := utils.GenerateJWT(user.ID, user.Role, sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+
+	return gin.H{
+		"token":              accessToken,
+		"token_type":         "Bearer",
+		"expires_in":         int(config.AppConfig.JWT.AccessTokenTTL.Seconds()),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": stored.ExpiresAt,
+		"session_id":         sessionID,
+	}, &stored, nil
+}
+
+// revokeReusedFamily revokes a refresh token family after one of its tokens was replayed
+func revokeReusedFamily(token models.RefreshToken) {
+	utils.Logger.Warnf("Refresh token reuse detected for user ID %d, revoking family %s", token.UserID, token.FamilyID)
+	if err := models.RevokeRefreshTokenFamily(token.FamilyID); err != nil {
+		utils.Logger.Errorf("Failed to revoke refresh token family: %v", err)
+	}
+}
+
+// VerifyEmail handles email verification
+func VerifyEmail(c *gin.Context) {
+	tokenStr := c.Query("token")
+	if tokenStr == "" {
+		// Render failure page with message
+		renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
 		return
 	}
 
-	// Delete the token after verification
-	if err := models.DB.Delete(&token).Error; err != nil {
-		utils.Logger.Errorf("Failed to delete email verification token: %v", err)
-		// Do not return error since email is already verified
+	// Consume the token and mark the email as verified together, so the link works only once
+	var user models.User
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		token, err := models.ConsumeToken(tx, utils.HashToken(tokenStr), models.TokenTypeEmailVerify)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("is_email_verified", true).Error
+	})
+	if err == models.ErrTokenInvalid {
+		// Render failure page with invalid or expired token message
+		renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
+		return
+	}
+	if err != nil {
+		utils.Logger.Errorf("Failed to verify email: %v", err)
+		// Render generic failure page
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
+		return
 	}
 
 	utils.Logger.Infof("Email verified successfully for user: %s", user.Email)
 
 	// Render email verification success page
-	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(successVerifyHTML))
+	renderPage(c, http.StatusOK, views.PageEmailVerified, nil)
 }
 
 // RequestPasswordReset handles password reset requests
@@ -308,23 +521,10 @@ func RequestPasswordReset(c *gin.Context) {
 		return
 	}
 
-	// Generate password reset token
-	resetToken, err := utils.GenerateRandomToken(32)
+	// Generate password reset token, replacing links sent earlier
+	resetToken, err := issueToken(models.DB, user.ID, models.TokenTypePasswordReset, time.Now().Add(24*time.Hour))
 	if err != nil {
-		utils.Logger.Errorf("Failed to generate password reset token: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to request password reset")
-		return
-	}
-
-	passwordResetToken := models.Token{
-		UserID:    user.ID,
-		Token:     resetToken,
-		Type:      models.TokenTypePasswordReset,
-		ExpiresAt: time.Now().Add(24 * time.Hour), // Token valid for 24 hours
-	}
-
-	if err := models.DB.Create(&passwordResetToken).Error; err != nil {
-		utils.Logger.Errorf("Failed to save password reset token: %v", err)
+		utils.Logger.Errorf("Failed to create password reset token: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to request password reset")
 		return
 	}
@@ -352,103 +552,37 @@ func ResetPasswordForm(c *gin.Context) {
 	tokenStr := c.Query("token")
 	if tokenStr == "" {
 		// Render failure page with message
-		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(failureHTML))
+		renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
 		return
 	}
 
-	var token models.Token
-	if err := models.DB.Where("token = ? AND type = ?", tokenStr, models.TokenTypePasswordReset).First(&token).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Render failure page with message
-			c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(failureHTML))
+	// Only check the token here; it is used up when the form is submitted
+	if _, err := models.FindValidToken(models.DB, utils.HashToken(tokenStr), models.TokenTypePasswordReset); err != nil {
+		if err == models.ErrTokenInvalid {
+			// Render failure page with invalid or expired token message
+			renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
 			return
 		}
 		utils.Logger.Errorf("Failed to verify reset token: %v", err)
 		// Render generic failure page
-		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(failureHTML))
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
 		return
 	}
 
-	if token.ExpiresAt.Before(time.Now()) {
-		// Render failure page with expired token message
-		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(failureHTML))
+	// The form is protected with a double-submit CSRF token: the same value goes into a cookie and a hidden field
+	csrfToken, err := utils.EnsureCSRFToken(c)
+	if err != nil {
+		utils.Logger.Errorf("Failed to issue CSRF token: %v", err)
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
 		return
 	}
 
-	// Render reset password page with form
-	// You can embed the token in the form as a hidden field
-	htmlContent := `
-	<!DOCTYPE html>
-	<html lang="en">
-	<head>
-		<meta charset="UTF-8">
-		<title>Reset Password</title>
-		<style>
-			body {
-				background-color: #f2f2f2;
-				display: flex;
-				justify-content: center;
-				align-items: center;
-				height: 100vh;
-				font-family: Arial, sans-serif;
-				margin: 0;
-			}
-			.container {
-				background-color: #ffffff;
-				padding: 40px;
-				box-shadow: 0 2px 8px rgba(0, 0, 0, 0.1);
-				border-radius: 8px;
-				text-align: center;
-			}
-			.container h1 {
-				color: #007bff;
-				font-size: 2em;
-				margin-bottom: 20px;
-			}
-			.container form {
-				display: flex;
-				flex-direction: column;
-			}
-			.container input {
-				padding: 10px;
-				margin: 10px 0;
-				border: 1px solid #ccc;
-				border-radius: 4px;
-				font-size: 1em;
-			}
-			.container button {
-				padding: 10px;
-				background-color: #28a745;
-				color: #ffffff;
-				border: none;
-				border-radius: 4px;
-				font-size: 1em;
-				cursor: pointer;
-				transition: background-color 0.3s ease;
-			}
-			.container button:hover {
-				background-color: #218838;
-			}
-		</style>
-	</head>
-	<body>
-		<div class="container">
-			<h1>Reset Your Password</h1>
-			<form action="/auth/reset-password" method="POST">
-				<input type="hidden" name="token" value="{{.Token}}">
-				<input type="password" name="new_password" placeholder="Enter New Password" required minlength="6">
-				<input type="password" name="confirm_password" placeholder="Confirm New Password" required minlength="6">
-				<button type="submit">Reset Password</button>
-			</form>
-		</div>
-	</body>
-	</html>
-	`
-
-	// Replace {{.Token}} with the actual token
-	finalHTML := strings.Replace(htmlContent, "{{.Token}}", tokenStr, 1)
-
-	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(finalHTML))
+	// Render reset password page with the token in a hidden field and the password policy
+	renderPage(c, http.StatusOK, views.PageResetPasswordForm, resetPasswordPage{
+		Token:     tokenStr,
+		CSRFToken: csrfToken,
+		Policy:    config.AppConfig.Password,
+	})
 }
 
 // ResetPassword handles resetting the user's password using the provided token (POST request)
@@ -457,40 +591,57 @@ func ResetPassword(c *gin.Context) {
 	// Bind form data to struct
 	if err := c.ShouldBind(&req); err != nil {
 		// Render failure page with message
-		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte("<p>"+"Invalid input: "+err.Error()+"</p>"))
+		renderResetPasswordFailed(c, http.StatusBadRequest, "invalid_input")
+		return
+	}
+
+	// Only accept forms rendered by ResetPasswordForm
+	if !utils.ValidCSRFToken(c, req.CSRFToken) {
+		renderResetPasswordFailed(c, http.StatusForbidden, "invalid_form")
 		return
 	}
 
 	// Validate that new_password and confirm_password match
 	if req.NewPassword != req.ConfirmPassword {
 		// Render failure page with message
-		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte("<p>Passwords do not match</p>"))
+		renderResetPasswordFailed(c, http.StatusBadRequest, "mismatch")
 		return
 	}
 
-	var token models.Token
-	if err := models.DB.Where("token = ? AND type = ?", req.Token, models.TokenTypePasswordReset).First(&token).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Render failure page with message
-			c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(failureHTML))
+	tokenHash := utils.HashToken(req.Token)
+	token, err := models.FindValidToken(models.DB, tokenHash, models.TokenTypePasswordReset)
+	if err != nil {
+		if err == models.ErrTokenInvalid {
+			// Render failure page with invalid or expired token message
+			renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
 			return
 		}
 		utils.Logger.Errorf("Failed to verify reset token: %v", err)
 		// Render generic failure page
-		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(failureHTML))
-		return
-	}
-
-	if token.ExpiresAt.Before(time.Now()) {
-		// Render failure page with expired token message
-		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(failureHTML))
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
 		return
 	}
 
 	var user models.User
 	if err := models.DB.First(&user, token.UserID).Error; err != nil {
 		// Render generic failure page
-		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(failureHTML))
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
+		return
+	}
+
+	violations, err := utils.ValidatePassword(req.NewPassword, user.Username, user.Email, user.ID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to validate password: %v", err)
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
+		return
+	}
+	if len(violations) > 0 {
+		// Render the violated rules so the user can go back and pick another password
+		reasons := make([]string, len(violations))
+		for i, violation := range violations {
+			reasons[i] = violation.Code
+		}
+		renderResetPasswordFailed(c, http.StatusBadRequest, reasons...)
 		return
 	}
 
@@ -499,31 +650,47 @@ func ResetPassword(c *gin.Context) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		utils.Logger.Errorf("Failed to hash password: %v", err)
-		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(failureHTML))
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
 		return
 	}
 
 	// Update password with the new hashed password
 	user.Password = string(hashedPassword)
+	user.PasswordResetRequired = false
 	// **Correction Ends Here**
 
-	if err := models.DB.Save(&user).Error; err != nil {
+	// Use up the token in the same transaction, so it cannot reset the password twice
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.ConsumeToken(tx, tokenHash, models.TokenTypePasswordReset); err != nil {
+			return err
+		}
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return recordPasswordHistory(tx, user.ID, user.Password)
+	})
+	if err == models.ErrTokenInvalid {
+		renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
+		return
+	}
+	if err != nil {
 		// Render failure page with message
 		utils.Logger.Errorf("Failed to update user password: %v", err)
-		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(failureHTML))
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
 		return
 	}
 
-	// Delete the token after use
-	if err := models.DB.Delete(&token).Error; err != nil {
-		utils.Logger.Errorf("Failed to delete reset token: %v", err)
-		// Do not return error since password is already changed
+	// Whoever may have taken over the account loses their sessions with the old password
+	if err := models.RevokeUserSessions(user.ID); err != nil {
+		utils.Logger.Errorf("Failed to revoke sessions after password reset: %v", err)
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
+		return
 	}
 
 	utils.Logger.Infof("Password reset successfully for user: %s", user.Email)
 
 	// Render password reset success page
-	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(successResetHTML))
+	renderPage(c, http.StatusOK, views.PagePasswordReset, nil)
 }
 
 // ResetPasswordAPI handles resetting the user's password via API (optional)
@@ -535,9 +702,10 @@ func ResetPasswordAPI(c *gin.Context) {
 		return
 	}
 
-	var token models.Token
-	if err := models.DB.Where("token = ? AND type = ?", req.Token, models.TokenTypePasswordReset).First(&token).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	tokenHash := utils.HashToken(req.Token)
+	token, err := models.FindValidToken(models.DB, tokenHash, models.TokenTypePasswordReset)
+	if err != nil {
+		if err == models.ErrTokenInvalid {
 			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired token")
 			return
 		}
@@ -546,11 +714,6 @@ func ResetPasswordAPI(c *gin.Context) {
 		return
 	}
 
-	if token.ExpiresAt.Before(time.Now()) {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Token has expired")
-		return
-	}
-
 	var user models.User
 	if err := models.DB.First(&user, token.UserID).Error; err != nil {
 		utils.Logger.Errorf("User not found for reset token: %v", err)
@@ -558,6 +721,10 @@ func ResetPasswordAPI(c *gin.Context) {
 		return
 	}
 
+	if !checkPasswordPolicy(c, req.NewPassword, user.Username, user.Email, user.ID) {
+		return
+	}
+
 	// **Correction Starts Here**
 	// Manually hash the new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
@@ -569,18 +736,34 @@ func ResetPasswordAPI(c *gin.Context) {
 
 	// Update password with the new hashed password
 	user.Password = string(hashedPassword)
+	user.PasswordResetRequired = false
 	// **Correction Ends Here**
 
-	if err := models.DB.Save(&user).Error; err != nil {
+	// Use up the token in the same transaction, so it cannot reset the password twice
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.ConsumeToken(tx, tokenHash, models.TokenTypePasswordReset); err != nil {
+			return err
+		}
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return recordPasswordHistory(tx, user.ID, user.Password)
+	})
+	if err == models.ErrTokenInvalid {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+	if err != nil {
 		utils.Logger.Errorf("Failed to update user password: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
 		return
 	}
 
-	// Delete the token after use
-	if err := models.DB.Delete(&token).Error; err != nil {
-		utils.Logger.Errorf("Failed to delete reset token: %v", err)
-		// Do not return error since password is already changed
+	// Whoever may have taken over the account loses their sessions with the old password
+	if err := models.RevokeUserSessions(user.ID); err != nil {
+		utils.Logger.Errorf("Failed to revoke sessions after password reset: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
 	}
 
 	utils.Logger.Infof("Password reset successfully for user: %s", user.Email)
@@ -591,170 +774,10 @@ func ResetPasswordAPI(c *gin.Context) {
 	})
 }
 
-// successVerifyHTML defines the HTML content for successful email verification
-const successVerifyHTML = `<!DOCTYPE html>
-<html lang="en">
-<head>
-	<meta charset="UTF-8">
-	<title>Email Verification Successful</title>
-	<style>
-		body {
-			background-color: #d4edda;
-			display: flex;
-			justify-content: center;
-			align-items: center;
-			height: 100vh;
-			font-family: Arial, sans-serif;
-			margin: 0;
-		}
-		.container {
-			background-color: #ffffff;
-			padding: 40px;
-			box-shadow: 0 2px 8px rgba(0, 0, 0, 0.1);
-			border-radius: 8px;
-			text-align: center;
-		}
-		.container h1 {
-			color: #155724;
-			font-size: 2em;
-			margin-bottom: 20px;
-		}
-		.container p {
-			font-size: 1em;
-			color: #333333;
-			margin-bottom: 30px;
-		}
-		.container .btn {
-			background-color: #28a745;
-			color: #ffffff;
-			padding: 15px 30px;
-			text-decoration: none;
-			font-size: 1em;
-			border-radius: 5px;
-			transition: background-color 0.3s ease;
-		}
-		.container .btn:hover {
-			background-color: #218838;
-		}
-	</style>
-</head>
-<body>
-	<div class="container">
-		<h1>Email Verified Successfully!</h1>
-		<p>Your email has been verified. You may now log in to your account.</p>
-		<a href="/auth/login" class="btn">Go to Login</a>
-	</div>
-</body>
-</html>`
-
-// failureHTML defines the HTML content for failed operations
-const failureHTML = `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <title>Operation Failed</title>
-    <style>
-        body {
-            background-color: #f8d7da;
-            display: flex;
-            justify-content: center;
-            align-items: center;
-            height: 100vh;
-            font-family: Arial, sans-serif;
-            margin: 0;
-        }
-        .content {
-            background-color: #ffffff;
-            padding: 40px;
-            box-shadow: 0 2px 8px rgba(0, 0, 0, 0.1);
-            border-radius: 8px;
-            text-align: center;
-        }
-        .content h1 {
-            color: #721c24;
-            font-size: 2em;
-            margin-bottom: 20px;
-        }
-        .content p {
-            font-size: 1em;
-            color: #333333;
-            margin-bottom: 30px;
-        }
-        .content .btn {
-            background-color: #721c24;
-            color: #ffffff;
-            padding: 15px 30px;
-            text-decoration: none;
-            font-size: 1em;
-            border-radius: 5px;
-            transition: background-color 0.3s ease;
-        }
-        .content .btn:hover {
-            background-color: #5a1a1a;
-        }
-    </style>
-</head>
-<body>
-    <div class="content">
-        <h1>Operation Failed!</h1>
-        <p>We're sorry, but the operation could not be completed. Please try again later.</p>
-        <a href="/auth/request-password-reset" class="btn">Request New Reset Link</a>
-    </div>
-</body>
-</html>`
-
-// successResetHTML defines the HTML content for successful password reset
-const successResetHTML = `<!DOCTYPE html>
-<html lang="en">
-<head>
-	<meta charset="UTF-8">
-	<title>Password Reset Successful</title>
-	<style>
-		body {
-			background-color: #d4edda;
-			display: flex;
-			justify-content: center;
-			align-items: center;
-			height: 100vh;
-			font-family: Arial, sans-serif;
-			margin: 0;
-		}
-		.container {
-			background-color: #ffffff;
-			padding: 40px;
-			box-shadow: 0 2px 8px rgba(0, 0, 0, 0.1);
-			border-radius: 8px;
-			text-align: center;
-		}
-		.container h1 {
-			color: #155724;
-			font-size: 2em;
-			margin-bottom: 20px;
-		}
-		.container p {
-			font-size: 1em;
-			color: #333333;
-			margin-bottom: 30px;
-		}
-		.container .btn {
-			background-color: #28a745;
-			color: #ffffff;
-			padding: 15px 30px;
-			text-decoration: none;
-			font-size: 1em;
-			border-radius: 5px;
-			transition: background-color 0.3s ease;
-		}
-		.container .btn:hover {
-			background-color: #218838;
-		}
-	</style>
-</head>
-<body>
-	<div class="container">
-		<h1>Password Reset Successful!</h1>
-		<p>Your password has been reset successfully. You may now log in with your new password.</p>
-		<a href="/auth/login" class="btn">Go to Login</a>
-	</div>
-</body>
-</html>`
+// renderResetPasswordFailed renders the page explaining why the submitted reset password form was rejected
+func renderResetPasswordFailed(c *gin.Context, status int, reasons ...string) {
+	renderPage(c, status, views.PageResetPasswordFailed, resetPasswordPage{
+		Reasons: reasons,
+		Policy:  config.AppConfig.Password,
+	})
+}
@@ -0,0 +1,63 @@
+// controllers/auth_cookie.go
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+)
+
+// authModeHeader lets browser clients ask for the tokens to be delivered as cookies only
+const authModeHeader = "X-Auth-Mode"
+
+// sendTokens writes the tokens issued by a login or a refresh.
+// With cookie sessions enabled the tokens are also stored in HttpOnly cookies and a CSRF token is returned;
+// clients sending "X-Auth-Mode: cookie" receive the tokens only as cookies, so they never reach JavaScript.
+// newSession rotates the CSRF token, a refresh keeps the current one so requests in flight stay valid.
+func sendTokens(c *gin.Context, tokens gin.H, newSession bool) {
+	if !config.AppConfig.Cookie.Enabled {
+		utils.SuccessResponse(c, tokens)
+		return
+	}
+
+	var (
+		csrfToken string
+		err       error
+	)
+	if newSession {
+		csrfToken, err = utils.IssueCSRFToken(c)
+	} else {
+		csrfToken, err = utils.EnsureCSRFToken(c)
+	}
+	if err != nil {
+		utils.Logger.Errorf("Failed to issue CSRF token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	utils.SetAuthCookies(c, tokens["token"].(string), tokens["refresh_token"].(string))
+	tokens["csrf_token"] = csrfToken
+	if c.GetHeader(authModeHeader) == "cookie" {
+		delete(tokens, "token")
+		delete(tokens, "refresh_token")
+	}
+
+	utils.SuccessResponse(c, tokens)
+}
+
+// CSRFToken handles GET /auth/csrf
+// It returns the CSRF token of the browser session, so a reloaded SPA can send it again in the X-CSRF-Token header.
+func CSRFToken(c *gin.Context) {
+	csrfToken, err := utils.EnsureCSRFToken(c)
+	if err != nil {
+		utils.Logger.Errorf("Failed to issue CSRF token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"csrf_token": csrfToken,
+	})
+}
@@ -0,0 +1,192 @@
+// controllers/avatar_controller.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/storage"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+)
+
+// MaxAvatarSize is the largest avatar upload accepted (5MB)
+const MaxAvatarSize = 5 << 20
+
+// AvatarController handles uploading and serving user avatars
+type AvatarController struct {
+	DB             *gorm.DB
+	StorageService storage.StorageService
+	BucketName     string
+}
+
+// NewAvatarController creates a new AvatarController instance
+func NewAvatarController(db *gorm.DB, storageService storage.StorageService, bucketName string) *AvatarController {
+	return &AvatarController{
+		DB:             db,
+		StorageService: storageService,
+		BucketName:     bucketName,
+	}
+}
+
+// UploadAvatar handles uploading or replacing the avatar of the current user.
+// The image is cropped to a square and stored as thumbnails in every size of utils.AvatarSizes.
+func (ac *AvatarController) UploadAvatar(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	// Get file from form
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Avatar file is required")
+		return
+	}
+	if file.Size > MaxAvatarSize {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Avatar size exceeds the limit of 5MB")
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to open file")
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxAvatarSize))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	// The file type is detected from its content, the Content-Type sent by the client is not trusted
+	thumbnails, err := utils.ResizeAvatar(data)
+	if err != nil {
+		if errors.Is(err, utils.ErrAvatarType) || errors.Is(err, utils.ErrAvatarTooLarge) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.Logger.Errorf("Failed to resize avatar: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process avatar")
+		return
+	}
+
+	// Every upload gets a new key, so replaced thumbnails are never served from a stale cache
+	avatarKey := fmt.Sprintf("avatars/%d/%s", user.ID, uuid.New().String())
+	for _, size := range utils.AvatarSizes {
+		objectName := utils.AvatarObjectName(avatarKey, size)
+		if _, err := ac.StorageService.UploadFile(context.Background(), ac.BucketName, objectName, bytes.NewReader(thumbnails[size]), "image/png"); err != nil {
+			utils.Logger.Errorf("Failed to upload avatar to storage: %v", err)
+			go ac.deleteAvatarObjects(avatarKey)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to upload avatar")
+			return
+		}
+	}
+
+	if err := ac.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("avatar_key", avatarKey).Error; err != nil {
+		utils.Logger.Errorf("Failed to save avatar: %v", err)
+		go ac.deleteAvatarObjects(avatarKey)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to save avatar")
+		return
+	}
+
+	// Remove the replaced avatar in the background, deleting from storage waits until the objects are gone
+	if user.AvatarKey != "" {
+		go ac.deleteAvatarObjects(user.AvatarKey)
+	}
+
+	utils.Logger.Infof("Avatar uploaded: UserID %d", user.ID)
+
+	utils.SuccessResponse(c, gin.H{
+		"avatar_url": models.AvatarPath(user.ID, avatarKey),
+		"sizes":      utils.AvatarSizes,
+	})
+}
+
+// DeleteAvatar handles removing the avatar of the current user
+func (ac *AvatarController) DeleteAvatar(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.AvatarKey == "" {
+		utils.ErrorResponse(c, http.StatusNotFound, "Avatar not found")
+		return
+	}
+
+	if err := ac.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("avatar_key", "").Error; err != nil {
+		utils.Logger.Errorf("Failed to remove avatar: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove avatar")
+		return
+	}
+
+	go ac.deleteAvatarObjects(user.AvatarKey)
+
+	utils.Logger.Infof("Avatar removed: UserID %d", user.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "Avatar removed successfully"})
+}
+
+// GetAvatar handles GET /users/:user_id/avatar?size=128
+// It is public so avatars can be used directly in <img> tags, and redirects to a short-lived URL of the thumbnail.
+func (ac *AvatarController) GetAvatar(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	size := utils.DefaultAvatarSize
+	if sizeParam := c.Query("size"); sizeParam != "" {
+		size, err = strconv.Atoi(sizeParam)
+		if err != nil || !utils.ValidAvatarSize(size) {
+			utils.ErrorResponseWithDetails(c, http.StatusBadRequest, "Invalid avatar size", utils.AvatarSizes)
+			return
+		}
+	}
+
+	var user models.User
+	if err := ac.DB.Select("id", "avatar_key").First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Avatar not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to retrieve user: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve avatar")
+		return
+	}
+	if user.AvatarKey == "" {
+		utils.ErrorResponse(c, http.StatusNotFound, "Avatar not found")
+		return
+	}
+
+	presignedURL, err := ac.StorageService.GeneratePresignedURL(context.Background(), ac.BucketName, utils.AvatarObjectName(user.AvatarKey, size), 15*time.Minute)
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate presigned URL: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve avatar")
+		return
+	}
+
+	// The redirect may be cached for less time than the presigned URL stays valid
+	c.Header("Cache-Control", "public, max-age=600")
+	c.Redirect(http.StatusFound, presignedURL)
+}
+
+// deleteAvatarObjects removes the thumbnails of an avatar from storage, logging failures
+func (ac *AvatarController) deleteAvatarObjects(avatarKey string) {
+	if err := utils.DeleteAvatarObjects(context.Background(), ac.StorageService, ac.BucketName, avatarKey); err != nil {
+		utils.Logger.Errorf("Failed to delete avatar %s from storage: %v", avatarKey, err)
+	}
+}
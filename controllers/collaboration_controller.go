@@ -24,14 +24,6 @@ type UpdateCollaboratorRoleRequest struct {
 
 // AddCollaborator handles adding a new collaborator to a project
 func AddCollaborator(c *gin.Context) {
-	// Ambil user_id dari context yang sudah di-set oleh AuthMiddleware
-	currentUserID, exists := c.Get("user_id")
-	if !exists {
-		utils.Logger.Warn("User ID not found in context")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "User ID not found")
-		return
-	}
-
 	// Ambil parameter project_id dari URL
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -59,12 +51,6 @@ func AddCollaborator(c *gin.Context) {
 		return
 	}
 
-	// Cek apakah pengguna saat ini adalah pemilik proyek atau admin
-	if project.OwnerID != currentUserID.(uint) {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have permission to add collaborators to this project")
-		return
-	}
-
 	// Cek apakah pengguna yang akan ditambahkan ada
 	var user models.User
 	if err := models.DB.First(&user, req.UserID).Error; err != nil {
@@ -117,14 +103,6 @@ func AddCollaborator(c *gin.Context) {
 
 // RemoveCollaborator handles removing a collaborator from a project
 func RemoveCollaborator(c *gin.Context) {
-	// Ambil user_id dari context yang sudah di-set oleh AuthMiddleware
-	currentUserID, exists := c.Get("user_id")
-	if !exists {
-		utils.Logger.Warn("User ID not found in context")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "User ID not found")
-		return
-	}
-
 	// Ambil parameter project_id dan collaborator_id dari URL
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -152,12 +130,6 @@ func RemoveCollaborator(c *gin.Context) {
 		return
 	}
 
-	// Cek apakah pengguna saat ini adalah pemilik proyek atau admin
-	if project.OwnerID != currentUserID.(uint) {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have permission to remove collaborators from this project")
-		return
-	}
-
 	// Cek apakah kolaborator ada
 	var collaboration models.Collaboration
 	if err := models.DB.Where("project_id = ? AND user_id = ?", project.ID, collaboratorID).First(&collaboration).Error; err != nil {
@@ -185,14 +157,6 @@ func RemoveCollaborator(c *gin.Context) {
 
 // UpdateCollaboratorRole handles updating a collaborator's role within a project
 func UpdateCollaboratorRole(c *gin.Context) {
-	// Ambil user_id dari context yang sudah di-set oleh AuthMiddleware
-	currentUserID, exists := c.Get("user_id")
-	if !exists {
-		utils.Logger.Warn("User ID not found in context")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "User ID not found")
-		return
-	}
-
 	// Ambil parameter project_id dan collaborator_id dari URL
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -227,12 +191,6 @@ func UpdateCollaboratorRole(c *gin.Context) {
 		return
 	}
 
-	// Cek apakah pengguna saat ini adalah pemilik proyek atau admin
-	if project.OwnerID != currentUserID.(uint) {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have permission to update collaborators in this project")
-		return
-	}
-
 	// Cek apakah kolaborator ada
 	var collaboration models.Collaboration
 	if err := models.DB.Where("project_id = ? AND user_id = ?", project.ID, collaboratorID).First(&collaboration).Error; err != nil {
@@ -270,14 +228,6 @@ func UpdateCollaboratorRole(c *gin.Context) {
 
 // ListCollaborators handles retrieving all collaborators of a project
 func ListCollaborators(c *gin.Context) {
-	// Ambil user_id dari context yang sudah di-set oleh AuthMiddleware
-	currentUserID, exists := c.Get("user_id")
-	if !exists {
-		utils.Logger.Warn("User ID not found in context")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "User ID not found")
-		return
-	}
-
 	// Ambil parameter project_id dari URL
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -298,20 +248,6 @@ func ListCollaborators(c *gin.Context) {
 		return
 	}
 
-	// Cek apakah pengguna saat ini adalah pemilik proyek atau kolaborator
-	if project.OwnerID != currentUserID.(uint) {
-		var collaboration models.Collaboration
-		if err := models.DB.Where("project_id = ? AND user_id = ?", project.ID, currentUserID.(uint)).First(&collaboration).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-				return
-			}
-			utils.Logger.Errorf("Failed to check collaboration: %v", err)
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check access permissions")
-			return
-		}
-	}
-
 	// Ambil semua kolaborator dalam proyek
 	var collaborations []models.Collaboration
 	if err := models.DB.Preload("User").Where("project_id = ?", project.ID).Find(&collaborations).Error; err != nil {
@@ -0,0 +1,191 @@
+// controllers/email_controller.go
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"github.com/mfuadfakhruzzaki/backendaurauran/views"
+)
+
+// ResendVerificationRequest represents the request structure for resending the verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// resendVerificationMessage is returned for every resend request so it does not reveal which emails are registered
+const resendVerificationMessage = "If the email is registered and not yet verified, a verification link has been sent."
+
+// ResendVerification handles sending a new verification email to an unverified account
+func ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := models.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			utils.Logger.Errorf("Failed to find user for verification resend: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to resend verification email")
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"message": resendVerificationMessage})
+		return
+	}
+
+	if user.IsEmailVerified {
+		utils.SuccessResponse(c, gin.H{"message": resendVerificationMessage})
+		return
+	}
+
+	// Do not send another email to the same address while the previous one is recent
+	var recent int64
+	if err := models.DB.Model(&models.Token{}).
+		Where("user_id = ? AND type = ? AND created_at > ?", user.ID, models.TokenTypeEmailVerify,
+			time.Now().Add(-config.AppConfig.Security.VerificationResendCooldown)).
+		Count(&recent).Error; err != nil {
+		utils.Logger.Errorf("Failed to check recent verification tokens: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to resend verification email")
+		return
+	}
+	if recent > 0 {
+		utils.Logger.Warnf("Verification resend requested too soon for UserID %d", user.ID)
+		utils.SuccessResponse(c, gin.H{"message": resendVerificationMessage})
+		return
+	}
+
+	if err := sendVerificationEmail(user); err != nil {
+		utils.Logger.Errorf("Failed to resend verification email: %v", err)
+	} else {
+		utils.Logger.Infof("Verification email resent to: %s", user.Email)
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": resendVerificationMessage})
+}
+
+// sendVerificationEmail replaces any earlier verification tokens of the user with a new one and emails it
+func sendVerificationEmail(user models.User) error {
+	// Links from earlier emails stop working once a new one is sent
+	verifyToken, err := issueToken(models.DB, user.ID, models.TokenTypeEmailVerify, time.Now().Add(24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	emailService := utils.NewEmailService()
+	return emailService.SendVerificationEmail(user.Email, verifyToken)
+}
+
+// requestEmailChange stores newEmail as the pending email of the user, sends a confirmation link to the
+// new address and a notice to the current one. The email only changes once the link is opened.
+func requestEmailChange(user models.User, newEmail string) error {
+	var changeToken string
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Update("pending_email", newEmail).Error; err != nil {
+			return err
+		}
+
+		// Only the latest requested address can be confirmed
+		var err error
+		changeToken, err = issueToken(tx, user.ID, models.TokenTypeEmailChange, time.Now().Add(24*time.Hour))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create email change token: %w", err)
+	}
+
+	emailService := utils.NewEmailService()
+	if err := emailService.SendEmailChangeConfirmation(newEmail, changeToken); err != nil {
+		return fmt.Errorf("failed to send email change confirmation: %w", err)
+	}
+
+	// The change can still be confirmed without the notice, so only log failures
+	if err := emailService.SendEmailChangeNotice(user.Email, newEmail); err != nil {
+		utils.Logger.Errorf("Failed to send email change notice to old address: %v", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange handles confirming a pending email change through the link sent to the new address
+func ConfirmEmailChange(c *gin.Context) {
+	tokenStr := c.Query("token")
+	if tokenStr == "" {
+		renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
+		return
+	}
+	tokenHash := utils.HashToken(tokenStr)
+
+	token, err := models.FindValidToken(models.DB, tokenHash, models.TokenTypeEmailChange)
+	if err != nil {
+		if err == models.ErrTokenInvalid {
+			renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
+			return
+		}
+		utils.Logger.Errorf("Failed to verify email change token: %v", err)
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, token.UserID).Error; err != nil {
+		utils.Logger.Errorf("User not found for email change: %v", err)
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
+		return
+	}
+
+	if user.PendingEmail == "" {
+		renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
+		return
+	}
+
+	oldEmail := user.Email
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.ConsumeToken(tx, tokenHash, models.TokenTypeEmailChange); err != nil {
+			return err
+		}
+
+		// Opening the link proves ownership of the new address, so it is verified right away
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"email":             user.PendingEmail,
+			"pending_email":     "",
+			"is_email_verified": true,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Verification and change links issued for the old state must not be usable anymore
+		return tx.Where("user_id = ? AND type IN ?", user.ID,
+			[]models.TokenType{models.TokenTypeEmailChange, models.TokenTypeEmailVerify}).
+			Delete(&models.Token{}).Error
+	})
+	if err != nil {
+		if err == models.ErrTokenInvalid {
+			renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
+			return
+		}
+		if isUniqueConstraintError(err) {
+			// Another account took the address after the change was requested
+			utils.Logger.Warnf("Email change for UserID %d failed, address already in use", user.ID)
+			renderPage(c, http.StatusConflict, views.PageFailure, nil)
+			return
+		}
+		utils.Logger.Errorf("Failed to change email: %v", err)
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
+		return
+	}
+
+	utils.Logger.Infof("Email changed for UserID %d from %s to %s", user.ID, oldEmail, user.PendingEmail)
+
+	renderPage(c, http.StatusOK, views.PageEmailChanged, nil)
+}
@@ -312,9 +312,9 @@ func (fc *FileController) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	// Authorization: Ensure that the user deleting the file is the project owner or the uploader
-	if file.UploadedBy != user.ID && project.OwnerID != user.ID {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have permission to delete this file")
+	// Authorization: Files uploaded by others can only be deleted with the file.manage permission
+	if file.UploadedBy != user.ID && !hasPermission(c, models.PermissionFileManage) {
+		utils.ForbiddenResponse(c, string(models.PermissionFileManage))
 		return
 	}
 
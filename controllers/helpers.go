@@ -0,0 +1,177 @@
+// controllers/helpers.go
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"github.com/mfuadfakhruzzaki/backendaurauran/views"
+	"gorm.io/gorm"
+)
+
+// currentUser retrieves the User object set by AuthMiddleware.
+// It writes the error response itself, so callers only need to return when ok is false.
+func currentUser(c *gin.Context) (models.User, bool) {
+	userInterface, exists := c.Get(utils.ContextUserKey)
+	if !exists {
+		utils.Logger.Warn("User not found in context")
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return models.User{}, false
+	}
+
+	user, ok := userInterface.(models.User)
+	if !ok {
+		utils.Logger.Warn("User type assertion failed")
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+		return models.User{}, false
+	}
+
+	return user, true
+}
+
+// loadCurrentUser loads the complete row of the authenticated user from the database. The user set by
+// AuthMiddleware comes from a short-lived cache without credentials or TOTP state, so handlers that check
+// a password or second factor, show account state, or save the user use this instead of currentUser.
+func loadCurrentUser(c *gin.Context) (models.User, bool) {
+	cached, ok := currentUser(c)
+	if !ok {
+		return models.User{}, false
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, cached.ID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+			return models.User{}, false
+		}
+		utils.Logger.Errorf("Failed to load user: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+		return models.User{}, false
+	}
+
+	return user, true
+}
+
+// userAccount is a user together with the account state that models.User leaves out of its JSON.
+// It is only returned to the user themselves and to admins.
+type userAccount struct {
+	models.User
+	PendingEmail          string            `json:"pending_email,omitempty"`
+	Status                models.UserStatus `json:"status"`
+	PasswordResetRequired bool              `json:"password_reset_required"`
+	DeletionScheduledAt   *time.Time        `json:"deletion_scheduled_at,omitempty"`
+	LockedUntil           *time.Time        `json:"locked_until,omitempty"`
+}
+
+// newUserAccount returns the account view of a user
+func newUserAccount(user models.User) userAccount {
+	return userAccount{
+		User:                  user,
+		PendingEmail:          user.PendingEmail,
+		Status:                user.Status,
+		PasswordResetRequired: user.PasswordResetRequired,
+		DeletionScheduledAt:   user.DeletionScheduledAt,
+		LockedUntil:           user.LockedUntil,
+	}
+}
+
+// hasPermission reports whether the permissions resolved by RequirePermission for this request include permission.
+// Handlers use it for checks that depend on the request body or the loaded record, such as assigning a task to someone else.
+func hasPermission(c *gin.Context, permission models.Permission) bool {
+	value, exists := c.Get(utils.ContextPermissionsKey)
+	if !exists {
+		return false
+	}
+	permissions, ok := value.(models.PermissionSet)
+	return ok && permissions.Has(permission)
+}
+
+// userPreferences returns the preferences of the current user, loaded once per request.
+// A failure to load them is logged and the defaults are used, so the request can still be answered.
+// Preferences of other users are loaded with models.GetUserPreferences instead.
+func userPreferences(c *gin.Context, userID uint) models.UserPreferences {
+	if value, exists := c.Get(utils.ContextPreferencesKey); exists {
+		if prefs, ok := value.(models.UserPreferences); ok && prefs.UserID == userID {
+			return prefs
+		}
+	}
+
+	prefs, err := models.GetUserPreferences(userID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to retrieve preferences of UserID %d: %v", userID, err)
+		prefs = models.DefaultUserPreferences(userID)
+	}
+	c.Set(utils.ContextPreferencesKey, prefs)
+	return prefs
+}
+
+// inUserZone returns t in the time zone of the current user, used for deadlines in responses
+func inUserZone(c *gin.Context, t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	user, ok := c.Value(utils.ContextUserKey).(models.User)
+	if !ok {
+		return t
+	}
+	local := t.In(userPreferences(c, user.ID).Location())
+	return &local
+}
+
+// renderPage writes one of the HTML pages opened from links in emails, in the language asked for by the browser
+func renderPage(c *gin.Context, status int, name string, data interface{}) {
+	var body bytes.Buffer
+	if err := views.Render(&body, name, views.Language(c.GetHeader("Accept-Language")), data); err != nil {
+		utils.Logger.Errorf("Failed to render page %s: %v", name, err)
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", body.Bytes())
+}
+
+// checkPasswordPolicy validates a new password against the password policy.
+// It writes a 400 response listing the violated rules itself, so callers only need to return when ok is false.
+func checkPasswordPolicy(c *gin.Context, password, username, email string, userID uint) bool {
+	violations, err := utils.ValidatePassword(password, username, email, userID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to validate password: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+		return false
+	}
+	if len(violations) > 0 {
+		utils.ErrorResponseWithDetails(c, http.StatusBadRequest, "Password does not meet the password policy", violations)
+		return false
+	}
+	return true
+}
+
+// recordPasswordHistory remembers a newly set password hash so it cannot be reused
+func recordPasswordHistory(tx *gorm.DB, userID uint, passwordHash string) error {
+	return models.RecordPasswordHistory(tx, userID, passwordHash, config.AppConfig.Password.HistorySize)
+}
+
+// issueToken creates a single-use token of the given type for the user, replacing earlier ones of that type.
+// Only the digest is stored; the returned plain token is what gets sent to the user.
+func issueToken(tx *gorm.DB, userID uint, tokenType models.TokenType, expiresAt time.Time) (string, error) {
+	plainToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := models.IssueToken(tx, userID, tokenType, utils.HashToken(plainToken), expiresAt); err != nil {
+		return "", err
+	}
+	return plainToken, nil
+}
+
+// truncate shortens s to at most max bytes, used for client-supplied values stored in fixed-size columns
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,222 @@
+// controllers/invitation_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+)
+
+// errInvitationUnusable is returned when an invitation code cannot be redeemed
+var errInvitationUnusable = errors.New("invitation is invalid, expired, revoked or used up")
+
+// CreateInvitationRequest represents the request structure for creating an invitation
+type CreateInvitationRequest struct {
+	Role      models.Role `json:"role" binding:"required,oneof=admin manager member"`
+	TeamID    *uint       `json:"team_id" binding:"omitempty"`
+	ProjectID *uint       `json:"project_id" binding:"omitempty"`
+	ExpiresAt *time.Time  `json:"expires_at" binding:"omitempty"`
+	MaxUses   *int        `json:"max_uses" binding:"omitempty,gte=0"` // 0 means unlimited, default 1
+}
+
+// CreateInvitation handles creating a new invitation code (admin only)
+func CreateInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req CreateInvitationRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.TeamID != nil && req.ProjectID != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "An invitation can be scoped to a team or a project, not both")
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	// Make sure the scope exists
+	if req.TeamID != nil {
+		var team models.Team
+		if err := models.DB.First(&team, *req.TeamID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				utils.ErrorResponse(c, http.StatusNotFound, "Team not found")
+				return
+			}
+			utils.Logger.Errorf("Failed to retrieve team: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+			return
+		}
+	}
+	if req.ProjectID != nil {
+		var project models.Project
+		if err := models.DB.First(&project, *req.ProjectID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				utils.ErrorResponse(c, http.StatusNotFound, "Project not found")
+				return
+			}
+			utils.Logger.Errorf("Failed to retrieve project: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+			return
+		}
+	}
+
+	code, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate invitation code: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+		return
+	}
+
+	maxUses := 1
+	if req.MaxUses != nil {
+		maxUses = *req.MaxUses
+	}
+
+	invitation := models.Invitation{
+		Code:        code,
+		Role:        req.Role,
+		TeamID:      req.TeamID,
+		ProjectID:   req.ProjectID,
+		CreatedByID: &user.ID,
+		ExpiresAt:   req.ExpiresAt,
+		MaxUses:     maxUses,
+	}
+
+	if err := models.DB.Create(&invitation).Error; err != nil {
+		utils.Logger.Errorf("Failed to create invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+		return
+	}
+
+	utils.Logger.Infof("Invitation created: InvitationID %d with role %s by UserID %d", invitation.ID, invitation.Role, user.ID)
+
+	utils.CreatedResponse(c, invitation)
+}
+
+// ListInvitations handles listing invitation codes (admin only).
+// Pass ?active=true to only list invitations that can still be redeemed.
+func ListInvitations(c *gin.Context) {
+	query := models.DB.Order("created_at desc")
+	if c.Query("active") == "true" {
+		query = query.Where("revoked_at IS NULL").
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+			Where("max_uses = 0 OR use_count < max_uses")
+	}
+
+	var invitations []models.Invitation
+	if err := query.Find(&invitations).Error; err != nil {
+		utils.Logger.Errorf("Failed to retrieve invitations: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve invitations")
+		return
+	}
+
+	utils.SuccessResponse(c, invitations)
+}
+
+// RevokeInvitation handles revoking an invitation code (admin only)
+func RevokeInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	invitationID, err := strconv.ParseUint(c.Param("invitation_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	var invitation models.Invitation
+	if err := models.DB.First(&invitation, uint(invitationID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Invitation not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to retrieve invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke invitation")
+		return
+	}
+
+	if invitation.RevokedAt != nil {
+		utils.ErrorResponse(c, http.StatusConflict, "Invitation is already revoked")
+		return
+	}
+
+	now := time.Now()
+	invitation.RevokedAt = &now
+	if err := models.DB.Model(&invitation).Update("revoked_at", now).Error; err != nil {
+		utils.Logger.Errorf("Failed to revoke invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke invitation")
+		return
+	}
+
+	utils.Logger.Infof("Invitation revoked: InvitationID %d by UserID %d", invitation.ID, user.ID)
+
+	utils.SuccessResponse(c, invitation)
+}
+
+// redeemInvitation consumes one use of the invitation identified by code inside tx.
+// The use counter is incremented with a conditional update so concurrent
+// registrations cannot exceed MaxUses.
+func redeemInvitation(tx *gorm.DB, code string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := tx.Where("code = ?", code).First(&invitation).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errInvitationUnusable
+		}
+		return nil, err
+	}
+
+	if !invitation.IsUsable(time.Now()) {
+		return nil, errInvitationUnusable
+	}
+
+	result := tx.Model(&models.Invitation{}).
+		Where("id = ? AND revoked_at IS NULL AND (max_uses = 0 OR use_count < max_uses)", invitation.ID).
+		Update("use_count", gorm.Expr("use_count + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errInvitationUnusable
+	}
+
+	return &invitation, nil
+}
+
+// joinInvitationScope adds the user to the team or project the invitation is scoped to
+func joinInvitationScope(tx *gorm.DB, invitation *models.Invitation, user *models.User) error {
+	if invitation.TeamID != nil {
+		team := models.Team{ID: *invitation.TeamID}
+		if err := tx.Model(&team).Association("Members").Append(user); err != nil {
+			return err
+		}
+	}
+
+	if invitation.ProjectID != nil {
+		collaboration := models.Collaboration{
+			ProjectID: *invitation.ProjectID,
+			UserID:    user.ID,
+			Role:      models.CollaborationRoleCollaborator,
+		}
+		if err := tx.Create(&collaboration).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+// controllers/jwks_controller.go
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+)
+
+// JWKS publishes the public keys that verify access tokens, so other services can
+// validate tokens without sharing a secret. The body is a plain JSON Web Key Set as
+// expected by JWT libraries, not wrapped in the usual response envelope.
+func JWKS(c *gin.Context) {
+	jwks, err := utils.Tokens.JWKS()
+	if err != nil {
+		utils.Logger.Errorf("Failed to build JWKS: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load signing keys")
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(utils.JWKSMaxAge.Seconds())))
+	c.JSON(http.StatusOK, jwks)
+}
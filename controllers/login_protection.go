@@ -0,0 +1,236 @@
+// controllers/login_protection.go
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"github.com/mfuadfakhruzzaki/backendaurauran/views"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordLoginAttempt stores a login attempt for per-IP throttling. Failures to store are only logged.
+func recordLoginAttempt(c *gin.Context, email string, userID *uint, success bool) {
+	attempt := models.LoginAttempt{
+		Email:     email,
+		IPAddress: c.ClientIP(),
+		UserID:    userID,
+		Success:   success,
+	}
+	if err := models.DB.Create(&attempt).Error; err != nil {
+		utils.Logger.Errorf("Failed to record login attempt: %v", err)
+	}
+}
+
+// checkIPLoginThrottle rejects the request when the client IP has too many recent failed logins.
+// It returns false when a response has already been written.
+func checkIPLoginThrottle(c *gin.Context) bool {
+	security := config.AppConfig.Security
+	since := time.Now().Add(-security.LoginAttemptWindow)
+
+	failures, err := models.CountFailedLoginAttemptsByIP(c.ClientIP(), since)
+	if err != nil {
+		utils.Logger.Errorf("Failed to count login attempts: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login")
+		return false
+	}
+
+	if failures >= int64(security.LoginIPMaxAttempts) {
+		utils.Logger.Warnf("Too many failed login attempts from IP %s", c.ClientIP())
+		c.Header("Retry-After", strconv.Itoa(int(security.LoginAttemptWindow.Seconds())))
+		utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed login attempts. Please try again later.")
+		return false
+	}
+
+	return true
+}
+
+// checkAccountLoginThrottle rejects the request when the account is locked or still inside
+// its back-off window. It returns false when a response has already been written.
+func checkAccountLoginThrottle(c *gin.Context, user models.User) bool {
+	now := time.Now()
+
+	if user.IsLocked(now) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(user.LockedUntil.Sub(now).Seconds()))))
+		utils.ErrorResponse(c, http.StatusLocked, "Account is temporarily locked due to too many failed login attempts")
+		return false
+	}
+
+	if user.FailedLoginAttempts > 0 && user.LastFailedLoginAt != nil {
+		retryAt := user.LastFailedLoginAt.Add(loginBackoff(user.FailedLoginAttempts))
+		if retryAt.After(now) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAt.Sub(now).Seconds()))))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed login attempts. Please wait before trying again.")
+			return false
+		}
+	}
+
+	return true
+}
+
+// loginBackoff returns how long a user must wait after the given number of consecutive failures.
+// The delay doubles with every failure and never exceeds the lockout duration.
+func loginBackoff(failures int) time.Duration {
+	security := config.AppConfig.Security
+	if failures <= 0 {
+		return 0
+	}
+
+	backoff := security.LoginBackoffBase
+	for i := 1; i < failures && backoff < security.LoginLockoutDuration; i++ {
+		backoff *= 2
+	}
+	if backoff > security.LoginLockoutDuration {
+		backoff = security.LoginLockoutDuration
+	}
+	return backoff
+}
+
+// registerFailedLogin increments the failure counter of the user and locks the account
+// once the configured threshold is reached. It returns true if the account is locked after this failure.
+// The counter and the lock are written in one statement, so concurrent failures cannot
+// all read the same count and slip past the threshold.
+func registerFailedLogin(c *gin.Context, user *models.User) bool {
+	security := config.AppConfig.Security
+	now := time.Now()
+	// Truncated to the precision the database stores, so the returned value can be compared
+	lockedUntil := now.Add(security.LoginLockoutDuration).Truncate(time.Microsecond)
+
+	recordLoginAttempt(c, user.Email, &user.ID, false)
+
+	updated := models.User{ID: user.ID}
+	err := models.DB.Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}, {Name: "locked_until"}}}).
+		Updates(map[string]interface{}{
+			"failed_login_attempts": gorm.Expr("failed_login_attempts + 1"),
+			"last_failed_login_at":  now,
+			"locked_until": gorm.Expr(
+				"CASE WHEN failed_login_attempts + 1 >= ? AND (locked_until IS NULL OR locked_until <= ?) THEN ? ELSE locked_until END",
+				security.LoginMaxAttempts, now, lockedUntil,
+			),
+		}).Error
+	if err != nil {
+		utils.Logger.Errorf("Failed to update failed login counter: %v", err)
+		return false
+	}
+
+	user.FailedLoginAttempts = updated.FailedLoginAttempts
+	user.LastFailedLoginAt = &now
+	user.LockedUntil = updated.LockedUntil
+
+	// Only the request whose update set the lock sends the unlock email
+	if updated.LockedUntil != nil && updated.LockedUntil.Equal(lockedUntil) {
+		utils.Logger.Warnf("Account locked after %d failed login attempts: UserID %d", user.FailedLoginAttempts, user.ID)
+		if err := sendUnlockAccountEmail(*user); err != nil {
+			utils.Logger.Errorf("Failed to send unlock account email: %v", err)
+		}
+	}
+
+	return user.IsLocked(now)
+}
+
+// registerSuccessfulLogin clears the failure counters of the user after a successful login.
+// Logging in during the deletion grace period keeps the account.
+func registerSuccessfulLogin(c *gin.Context, user *models.User) {
+	recordLoginAttempt(c, user.Email, &user.ID, true)
+
+	if user.DeletionScheduledAt != nil {
+		if err := models.CancelAccountDeletion(user.ID); err != nil {
+			utils.Logger.Errorf("Failed to cancel account deletion: %v", err)
+		} else {
+			utils.Logger.Infof("Account deletion cancelled by login: UserID %d", user.ID)
+			user.DeletionScheduledAt = nil
+		}
+	}
+
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := clearLoginLockout(models.DB, user.ID); err != nil {
+		utils.Logger.Errorf("Failed to reset failed login counter: %v", err)
+		return
+	}
+	user.FailedLoginAttempts = 0
+	user.LastFailedLoginAt = nil
+	user.LockedUntil = nil
+}
+
+// checkAccountStatus rejects accounts disabled by an admin or waiting for a forced password reset.
+// It writes the error response itself and returns false when the account may not log in.
+func checkAccountStatus(c *gin.Context, user models.User) bool {
+	if user.IsDisabled() {
+		utils.Logger.Warnf("Login attempt for disabled account: UserID %d", user.ID)
+		utils.ErrorResponse(c, http.StatusForbidden, "Account is disabled")
+		return false
+	}
+	if user.PasswordResetRequired {
+		utils.ErrorResponse(c, http.StatusForbidden, "Password reset required. Use the reset link sent to your email.")
+		return false
+	}
+	return true
+}
+
+// clearLoginLockout resets the failure counter and lockout of a user
+func clearLoginLockout(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	}).Error
+}
+
+// sendUnlockAccountEmail creates an unlock token and emails it to the locked-out user
+func sendUnlockAccountEmail(user models.User) error {
+	unlockToken, err := issueToken(models.DB, user.ID, models.TokenTypeAccountUnlock, *user.LockedUntil)
+	if err != nil {
+		return fmt.Errorf("failed to create unlock token: %w", err)
+	}
+
+	prefs, err := models.GetUserPreferences(user.ID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to retrieve preferences of UserID %d: %v", user.ID, err)
+		prefs = models.DefaultUserPreferences(user.ID)
+	}
+
+	emailService := utils.NewEmailService()
+	return emailService.SendUnlockAccountEmail(user.Email, unlockToken, *user.LockedUntil, prefs)
+}
+
+// UnlockAccount handles unlocking an account through the link sent by email
+func UnlockAccount(c *gin.Context) {
+	tokenStr := c.Query("token")
+	if tokenStr == "" {
+		renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
+		return
+	}
+
+	var token *models.Token
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if token, err = models.ConsumeToken(tx, utils.HashToken(tokenStr), models.TokenTypeAccountUnlock); err != nil {
+			return err
+		}
+		return clearLoginLockout(tx, token.UserID)
+	})
+	if err == models.ErrTokenInvalid {
+		renderPage(c, http.StatusBadRequest, views.PageFailure, nil)
+		return
+	}
+	if err != nil {
+		utils.Logger.Errorf("Failed to unlock account: %v", err)
+		renderPage(c, http.StatusInternalServerError, views.PageFailure, nil)
+		return
+	}
+
+	utils.Logger.Infof("Account unlocked via email link: UserID %d", token.UserID)
+
+	renderPage(c, http.StatusOK, views.PageAccountUnlocked, nil)
+}
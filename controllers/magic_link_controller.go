@@ -0,0 +1,125 @@
+// controllers/magic_link_controller.go
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+)
+
+// MagicLinkRequest represents the request structure for requesting a login link by email
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerifyMagicLinkRequest represents the request structure for exchanging a login link token
+type VerifyMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// magicLinkMessage is returned for every magic link request so it does not reveal which emails are registered
+const magicLinkMessage = "If the email is registered and allowed to use login links, a login link has been sent."
+
+// RequestMagicLink handles sending a single-use login link to the user's email
+func RequestMagicLink(c *gin.Context) {
+	var req MagicLinkRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := models.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			utils.Logger.Errorf("Failed to find user for magic link: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send login link")
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"message": magicLinkMessage})
+		return
+	}
+
+	if !magicLinkAllowed(user) || !user.IsEmailVerified || user.IsLocked(time.Now()) {
+		utils.Logger.Warnf("Magic link not sent for UserID %d", user.ID)
+		utils.SuccessResponse(c, gin.H{"message": magicLinkMessage})
+		return
+	}
+
+	// Only the most recent link can be used
+	ttl := config.AppConfig.Security.MagicLinkTTL
+	loginToken, err := issueToken(models.DB, user.ID, models.TokenTypeMagicLogin, time.Now().Add(ttl))
+	if err != nil {
+		utils.Logger.Errorf("Failed to create magic link token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send login link")
+		return
+	}
+
+	emailService := utils.NewEmailService()
+	if err := emailService.SendMagicLinkEmail(user.Email, loginToken, ttl); err != nil {
+		utils.Logger.Errorf("Failed to send magic link email: %v", err)
+	} else {
+		utils.Logger.Infof("Magic link sent to: %s", user.Email)
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": magicLinkMessage})
+}
+
+// VerifyMagicLink handles exchanging a login link token for the normal login response.
+// Two-factor authentication still applies to accounts that enabled it.
+func VerifyMagicLink(c *gin.Context) {
+	var req VerifyMagicLinkRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Consuming the token deletes it, so a link works only once even when opened twice at the same time
+	token, err := models.ConsumeToken(models.DB, utils.HashToken(req.Token), models.TokenTypeMagicLogin)
+	if err != nil {
+		if err == models.ErrTokenInvalid {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired login link")
+			return
+		}
+		utils.Logger.Errorf("Failed to consume magic link token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login")
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, token.UserID).Error; err != nil {
+		utils.Logger.Errorf("User not found for magic link: %v", err)
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired login link")
+		return
+	}
+
+	// The role may have changed since the link was sent
+	if !magicLinkAllowed(user) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Login links are not available for this account")
+		return
+	}
+
+	if !checkAccountLoginThrottle(c, user) {
+		recordLoginAttempt(c, user.Email, &user.ID, false)
+		return
+	}
+
+	finishLogin(c, user)
+}
+
+// magicLinkAllowed reports whether the role of the user may log in with a magic link
+func magicLinkAllowed(user models.User) bool {
+	for _, role := range config.AppConfig.Security.MagicLinkRoles {
+		if models.Role(role) == user.Role {
+			return true
+		}
+	}
+	return false
+}
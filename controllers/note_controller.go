@@ -47,18 +47,6 @@ func CreateNote(c *gin.Context) {
 		return
 	}
 
-	// Check if the project exists and the user has access
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create note")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var req CreateNoteRequest
 	// Bind JSON request to struct
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -100,21 +88,6 @@ func CreateNote(c *gin.Context) {
 
 // ListNotes handles retrieving all notes within a project
 func ListNotes(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-
 	// Retrieve project_id from URL parameters
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -123,18 +96,6 @@ func ListNotes(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve notes")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var notes []models.Note
 	// Retrieve all notes for the project, including the User who created each note
 	if err := models.DB.Where("project_id = ?", uint(projectID)).
@@ -164,21 +125,6 @@ func ListNotes(c *gin.Context) {
 
 // GetNote handles retrieving a single note by ID within a project
 func GetNote(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-
 	// Retrieve project_id and note_id from URL parameters
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -194,18 +140,6 @@ func GetNote(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve note")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var note models.Note
 	// Retrieve the note from the database
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(noteID), uint(projectID)).
@@ -278,18 +212,6 @@ func UpdateNote(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update note")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var note models.Note
 	// Retrieve the note from the database
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(noteID), uint(projectID)).First(&note).Error; err != nil {
@@ -363,18 +285,6 @@ func DeleteNote(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete note")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var note models.Note
 	// Retrieve the note from the database
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(noteID), uint(projectID)).First(&note).Error; err != nil {
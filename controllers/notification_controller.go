@@ -88,7 +88,7 @@ func (nc *NotificationController) CreateNotification(c *gin.Context) {
 		return
 	}
 
-	// Check if the project exists and if the sender has access to it
+	// Check if the project exists
 	var project models.Project
 	if err := nc.DB.Preload("Owner").First(&project, projectID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -100,9 +100,6 @@ func (nc *NotificationController) CreateNotification(c *gin.Context) {
 		return
 	}
 
-	// Optional: Check if the sender has access to the project (e.g., is owner or team member)
-	// Implement this check based on your application's authorization logic
-
 	// If IsRead is not provided, default to false
 	isRead := false
 	if req.IsRead != nil {
@@ -0,0 +1,387 @@
+// controllers/oauth_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// usernameInvalidChars matches characters not allowed in usernames derived from provider claims
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// OAuthStart redirects the browser to the authorization endpoint of the provider
+func OAuthStart(c *gin.Context) {
+	authURL, ok := createOAuthState(c, c.Param("provider"), nil)
+	if !ok {
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback handles the redirect back from the provider. Depending on the stored state it
+// either logs the user in (creating an account on first use) or links the identity to the
+// user who started the flow from their profile.
+func OAuthCallback(c *gin.Context) {
+	providerName := c.Param("provider")
+
+	if providerError := c.Query("error"); providerError != "" {
+		utils.Logger.Warnf("OAuth provider %s returned error: %s (%s)", providerName, providerError, c.Query("error_description"))
+		utils.ErrorResponse(c, http.StatusBadRequest, "Authorization was denied or failed at the provider")
+		return
+	}
+
+	code := c.Query("code")
+	stateValue := c.Query("state")
+	if code == "" || stateValue == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Missing code or state")
+		return
+	}
+
+	// The state must come back to the browser that started the flow, otherwise a callback URL
+	// prepared by someone else could log this browser into their account
+	if !utils.ValidOAuthStateCookie(c, stateValue) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired OAuth state")
+		return
+	}
+	utils.ClearOAuthStateCookie(c)
+
+	state, err := consumeOAuthState(providerName, stateValue)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired OAuth state")
+			return
+		}
+		utils.Logger.Errorf("Failed to load OAuth state: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to complete OAuth login")
+		return
+	}
+
+	provider, err := utils.GetOIDCProvider(c.Request.Context(), providerName)
+	if err != nil {
+		utils.Logger.Errorf("Failed to load OAuth provider %s: %v", providerName, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "OAuth provider is unavailable")
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		utils.Logger.Warnf("OAuth code exchange with %s failed: %v", providerName, err)
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Failed to verify the identity returned by the provider")
+		return
+	}
+
+	if state.UserID != nil {
+		linkOAuthIdentity(c, *state.UserID, providerName, claims)
+		return
+	}
+
+	loginWithOAuthIdentity(c, providerName, claims)
+}
+
+// ListIdentities handles listing the external identities linked to the current user
+func ListIdentities(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var identities []models.UserIdentity
+	if err := models.DB.Where("user_id = ?", user.ID).Order("created_at asc").Find(&identities).Error; err != nil {
+		utils.Logger.Errorf("Failed to retrieve identities: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve linked accounts")
+		return
+	}
+
+	utils.SuccessResponse(c, identities)
+}
+
+// LinkIdentity starts linking a provider account to the current user. The client must
+// open the returned authorization URL in the browser.
+func LinkIdentity(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	authURL, ok := createOAuthState(c, c.Param("provider"), &user.ID)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"authorization_url": authURL})
+}
+
+// UnlinkIdentity handles removing a linked external identity from the current user
+func UnlinkIdentity(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	identityID, err := strconv.ParseUint(c.Param("identity_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid identity ID")
+		return
+	}
+
+	result := models.DB.Where("id = ? AND user_id = ?", uint(identityID), user.ID).Delete(&models.UserIdentity{})
+	if result.Error != nil {
+		utils.Logger.Errorf("Failed to unlink identity: %v", result.Error)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to unlink account")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Linked account not found")
+		return
+	}
+
+	utils.Logger.Infof("Identity unlinked: IdentityID %d by UserID %d", identityID, user.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "Account unlinked successfully"})
+}
+
+// createOAuthState stores a new state, nonce and PKCE verifier, binds the state to the browser with
+// a cookie and returns the authorization URL. It returns false when a response has already been written.
+func createOAuthState(c *gin.Context, providerName string, userID *uint) (string, bool) {
+	provider, err := utils.GetOIDCProvider(c.Request.Context(), providerName)
+	if err != nil {
+		if errors.Is(err, utils.ErrUnknownOIDCProvider) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Unknown OAuth provider")
+			return "", false
+		}
+		utils.Logger.Errorf("Failed to load OAuth provider %s: %v", providerName, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "OAuth provider is unavailable")
+		return "", false
+	}
+
+	stateValue, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate OAuth state: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start OAuth login")
+		return "", false
+	}
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate OAuth nonce: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start OAuth login")
+		return "", false
+	}
+
+	now := time.Now()
+	state := models.OAuthState{
+		State:        stateValue,
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		UserID:       userID,
+		ExpiresAt:    now.Add(config.AppConfig.OAuth.StateTTL),
+	}
+	if err := models.DB.Create(&state).Error; err != nil {
+		utils.Logger.Errorf("Failed to save OAuth state: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start OAuth login")
+		return "", false
+	}
+
+	// Abandoned flows leave their state behind; clean those up opportunistically
+	if err := models.DB.Where("expires_at < ?", now).Delete(&models.OAuthState{}).Error; err != nil {
+		utils.Logger.Errorf("Failed to delete expired OAuth states: %v", err)
+	}
+
+	utils.SetOAuthStateCookie(c, state.State, config.AppConfig.OAuth.StateTTL)
+
+	return provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier), true
+}
+
+// consumeOAuthState loads and deletes the state so it can only be used once.
+// gorm.ErrRecordNotFound is returned for unknown, reused or expired states.
+func consumeOAuthState(providerName, stateValue string) (*models.OAuthState, error) {
+	var state models.OAuthState
+	if err := models.DB.Where("state = ? AND provider = ?", stateValue, providerName).First(&state).Error; err != nil {
+		return nil, err
+	}
+
+	result := models.DB.Where("id = ?", state.ID).Delete(&models.OAuthState{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || state.ExpiresAt.Before(time.Now()) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &state, nil
+}
+
+// linkOAuthIdentity links the verified provider subject to the user who started the flow
+func linkOAuthIdentity(c *gin.Context, userID uint, providerName string, claims *utils.OIDCClaims) {
+	var existing models.UserIdentity
+	err := models.DB.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID != userID {
+			utils.ErrorResponse(c, http.StatusConflict, "This account is already linked to another user")
+			return
+		}
+		utils.SuccessResponse(c, existing)
+		return
+	} else if err != gorm.ErrRecordNotFound {
+		utils.Logger.Errorf("Failed to check existing identity: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to link account")
+		return
+	}
+
+	identity := models.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := models.DB.Create(&identity).Error; err != nil {
+		utils.Logger.Errorf("Failed to link identity: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to link account")
+		return
+	}
+
+	utils.Logger.Infof("Identity linked: provider %s to UserID %d", providerName, userID)
+
+	utils.CreatedResponse(c, identity)
+}
+
+// loginWithOAuthIdentity logs in the user linked to the provider subject, creating a new
+// account when the subject is unknown and its email is not registered yet.
+func loginWithOAuthIdentity(c *gin.Context, providerName string, claims *utils.OIDCClaims) {
+	var identity models.UserIdentity
+	err := models.DB.Preload("User").Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&identity).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		utils.Logger.Errorf("Failed to find identity: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to complete OAuth login")
+		return
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		created, ok := createOAuthUser(c, providerName, claims)
+		if !ok {
+			return
+		}
+		identity = *created
+	}
+
+	user := *identity.User
+	if !checkAccountLoginThrottle(c, user) {
+		return
+	}
+
+	now := time.Now()
+	if err := models.DB.Model(&identity).Update("last_login_at", now).Error; err != nil {
+		utils.Logger.Errorf("Failed to update identity last login: %v", err)
+	}
+
+	finishLogin(c, user)
+}
+
+// createOAuthUser registers a new user for a provider subject that is not linked yet.
+// Existing accounts are never taken over by email: their owner has to log in and link the provider.
+func createOAuthUser(c *gin.Context, providerName string, claims *utils.OIDCClaims) (*models.UserIdentity, bool) {
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The provider did not return a verified email address")
+		return nil, false
+	}
+
+	var count int64
+	if err := models.DB.Model(&models.User{}).Where("email = ?", claims.Email).Count(&count).Error; err != nil {
+		utils.Logger.Errorf("Failed to check existing user: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to complete OAuth login")
+		return nil, false
+	}
+	if count > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "Email already registered. Log in with your password and link this provider from your profile.")
+		return nil, false
+	}
+
+	username, err := uniqueUsername(claims)
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate username: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to complete OAuth login")
+		return nil, false
+	}
+
+	// The account has no usable password until the user sets one through password reset
+	password, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate password: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to complete OAuth login")
+		return nil, false
+	}
+
+	user := models.User{
+		Username:        username,
+		Email:           claims.Email,
+		Password:        password,
+		Role:            models.RoleMember,
+		IsEmailVerified: true,
+	}
+	identity := models.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(&identity).Error
+	})
+	if err != nil {
+		utils.Logger.Errorf("Failed to create user from OAuth identity: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to complete OAuth login")
+		return nil, false
+	}
+
+	utils.Logger.Infof("User registered via %s: %s", providerName, user.Email)
+
+	identity.User = &user
+	return &identity, true
+}
+
+// uniqueUsername derives a username from the provider claims, adding a random suffix when it is taken
+func uniqueUsername(claims *utils.OIDCClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := models.DB.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+
+		suffix, err := utils.GenerateRandomToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + suffix
+	}
+
+	return "", errors.New("could not find a free username")
+}
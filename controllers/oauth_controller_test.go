@@ -0,0 +1,506 @@
+// controllers/oauth_controller_test.go
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const stubClientID = "aurauran-test"
+
+// stubIssuer is a local OpenID Connect provider serving discovery, JWKS and token endpoints.
+// Codes are registered by the test with the claims the ID token should carry.
+type stubIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]jwt.MapClaims
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	issuer := &stubIssuer{key: key, codes: make(map[string]jwt.MapClaims)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		issuer.mu.Lock()
+		claims, ok := issuer.codes[r.PostForm.Get("code")]
+		delete(issuer.codes, r.PostForm.Get("code"))
+		issuer.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":"invalid_grant"}`)
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "stub"
+		idToken, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "stub-access-token",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+
+	return issuer
+}
+
+// issueCode registers an authorization code for the subject, as if the user logged in at the provider
+func (s *stubIssuer) issueCode(subject, email, nonce string) string {
+	now := time.Now()
+	code := fmt.Sprintf("code-%s-%d", subject, now.UnixNano())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            stubClientID,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+	return code
+}
+
+// oauthTest holds the router and provider of one test
+type oauthTest struct {
+	router   *gin.Engine
+	issuer   *stubIssuer
+	provider string
+}
+
+// setupOAuthTest configures an in-memory database, a stub issuer and a router with the OAuth routes.
+// Requests to /users carry the ID of the authenticated user in the X-Test-User header.
+func setupOAuthTest(t *testing.T) *oauthTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	utils.Logger = logrus.New()
+	utils.Logger.SetOutput(io.Discard)
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := models.SetupTeamMembership(db); err != nil {
+		t.Fatalf("failed to set up team membership: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.UserIdentity{},
+		&models.OAuthState{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.SigningKey{},
+		&models.LoginAttempt{},
+		&models.Token{},
+	); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	models.InitModels(db)
+
+	issuer := newStubIssuer(t)
+	// Providers are cached by name, so every test gets its own
+	provider := strings.ToLower(strings.NewReplacer("/", "-", "_", "-").Replace(t.Name()))
+
+	config.AppConfig = &config.Config{
+		JWT: config.JWTConfig{
+			Secret:          "test-secret-test-secret-test-secret",
+			Algorithm:       "RS256",
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: time.Hour,
+		},
+		Security: config.SecurityConfig{
+			LoginMaxAttempts:     5,
+			LoginIPMaxAttempts:   20,
+			LoginAttemptWindow:   time.Minute,
+			LoginLockoutDuration: time.Minute,
+			LoginBackoffBase:     time.Second,
+		},
+		OAuth: config.OAuthConfig{
+			Providers: map[string]config.OAuthProviderConfig{
+				provider: {
+					Name:        provider,
+					Issuer:      issuer.URL,
+					ClientID:    stubClientID,
+					RedirectURL: "http://localhost/auth/oauth/" + provider + "/callback",
+					Scopes:      []string{"openid", "email"},
+				},
+			},
+			StateTTL: 10 * time.Minute,
+		},
+	}
+	if err := utils.InitTokenService(); err != nil {
+		t.Fatalf("failed to initialize token service: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/auth/oauth/:provider/start", OAuthStart)
+	router.GET("/auth/oauth/:provider/callback", OAuthCallback)
+	users := router.Group("/users", func(c *gin.Context) {
+		var user models.User
+		if err := models.DB.First(&user, c.GetHeader("X-Test-User")).Error; err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(utils.ContextUserKey, user)
+	})
+	users.POST("/identities/:provider/link", LinkIdentity)
+	users.DELETE("/identities/:identity_id", UnlinkIdentity)
+
+	return &oauthTest{router: router, issuer: issuer, provider: provider}
+}
+
+// do sends a request with the given cookies and optional authenticated user
+func (o *oauthTest) do(method, target string, userID uint, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if userID != 0 {
+		req.Header.Set("X-Test-User", fmt.Sprint(userID))
+	}
+	w := httptest.NewRecorder()
+	o.router.ServeHTTP(w, req)
+	return w
+}
+
+// start begins a login flow and returns the state, nonce and the cookies set for the browser
+func (o *oauthTest) start(t *testing.T) (string, string, []*http.Cookie) {
+	t.Helper()
+	w := o.do(http.MethodGet, "/auth/oauth/"+o.provider+"/start", 0, nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("start: expected 302, got %d: %s", w.Code, w.Body.String())
+	}
+	return parseAuthorizationURL(t, w.Header().Get("Location"), w)
+}
+
+// link begins linking the provider to the user and returns the state, nonce and cookies
+func (o *oauthTest) link(t *testing.T, userID uint) (string, string, []*http.Cookie) {
+	t.Helper()
+	w := o.do(http.MethodPost, "/users/identities/"+o.provider+"/link", userID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("link: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data struct {
+			AuthorizationURL string `json:"authorization_url"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return parseAuthorizationURL(t, body.Data.AuthorizationURL, w)
+}
+
+// callback returns from the provider to the callback route
+func (o *oauthTest) callback(code, state string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	query := url.Values{"code": {code}, "state": {state}}
+	return o.do(http.MethodGet, "/auth/oauth/"+o.provider+"/callback?"+query.Encode(), 0, cookies)
+}
+
+func parseAuthorizationURL(t *testing.T, authURL string, w *httptest.ResponseRecorder) (string, string, []*http.Cookie) {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil || parsed.Query().Get("state") == "" || parsed.Query().Get("nonce") == "" {
+		t.Fatalf("invalid authorization URL %q", authURL)
+	}
+	return parsed.Query().Get("state"), parsed.Query().Get("nonce"), w.Result().Cookies()
+}
+
+func createTestUser(t *testing.T, username, email string) models.User {
+	t.Helper()
+	user := models.User{
+		Username:        username,
+		Email:           email,
+		Password:        "Sup3r-secret-password",
+		Role:            models.RoleMember,
+		IsEmailVerified: true,
+		Status:          models.UserStatusActive,
+	}
+	if err := models.DB.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func TestConsumeOAuthState(t *testing.T) {
+	setupOAuthTest(t)
+
+	fresh := models.OAuthState{State: "fresh", Provider: "stub", Nonce: "n", CodeVerifier: "v", ExpiresAt: time.Now().Add(time.Minute)}
+	expired := models.OAuthState{State: "expired", Provider: "stub", Nonce: "n", CodeVerifier: "v", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := models.DB.Create(&[]models.OAuthState{fresh, expired}).Error; err != nil {
+		t.Fatalf("failed to create states: %v", err)
+	}
+
+	if _, err := consumeOAuthState("other", "fresh"); err != gorm.ErrRecordNotFound {
+		t.Fatalf("state of another provider: expected ErrRecordNotFound, got %v", err)
+	}
+	state, err := consumeOAuthState("stub", "fresh")
+	if err != nil || state.Nonce != "n" {
+		t.Fatalf("first use: expected the state, got %+v, %v", state, err)
+	}
+	if _, err := consumeOAuthState("stub", "fresh"); err != gorm.ErrRecordNotFound {
+		t.Fatalf("reuse: expected ErrRecordNotFound, got %v", err)
+	}
+	if _, err := consumeOAuthState("stub", "expired"); err != gorm.ErrRecordNotFound {
+		t.Fatalf("expired: expected ErrRecordNotFound, got %v", err)
+	}
+
+	var remaining int64
+	models.DB.Model(&models.OAuthState{}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected consumed and expired states to be deleted, %d left", remaining)
+	}
+}
+
+func TestOAuthCallbackCreatesAccountOnFirstLogin(t *testing.T) {
+	o := setupOAuthTest(t)
+
+	state, nonce, cookies := o.start(t)
+	w := o.callback(o.issuer.issueCode("alice-sub", "alice@example.com", nonce), state, cookies)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"refresh_token"`) {
+		t.Fatalf("expected tokens in the response: %s", w.Body.String())
+	}
+
+	var user models.User
+	if err := models.DB.Where("email = ?", "alice@example.com").First(&user).Error; err != nil {
+		t.Fatalf("expected the account to be created: %v", err)
+	}
+	if !user.IsEmailVerified || user.Role != models.RoleMember || user.Username != "alice" {
+		t.Fatalf("unexpected account: %+v", user)
+	}
+
+	var identity models.UserIdentity
+	if err := models.DB.Where("provider = ? AND subject = ?", o.provider, "alice-sub").First(&identity).Error; err != nil {
+		t.Fatalf("expected the identity to be linked: %v", err)
+	}
+	if identity.UserID != user.ID {
+		t.Fatalf("identity linked to UserID %d, expected %d", identity.UserID, user.ID)
+	}
+
+	// The second login finds the linked identity instead of creating another account
+	state, nonce, cookies = o.start(t)
+	w = o.callback(o.issuer.issueCode("alice-sub", "alice@example.com", nonce), state, cookies)
+	if w.Code != http.StatusOK {
+		t.Fatalf("second login: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	models.DB.Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected one account, got %d", count)
+	}
+}
+
+func TestOAuthCallbackRejectsExistingEmail(t *testing.T) {
+	o := setupOAuthTest(t)
+	createTestUser(t, "bob", "bob@example.com")
+
+	state, nonce, cookies := o.start(t)
+	w := o.callback(o.issuer.issueCode("bob-sub", "bob@example.com", nonce), state, cookies)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var count int64
+	models.DB.Model(&models.UserIdentity{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no identity to be linked, got %d", count)
+	}
+}
+
+func TestOAuthCallbackRequiresStateCookie(t *testing.T) {
+	o := setupOAuthTest(t)
+
+	state, nonce, _ := o.start(t)
+	w := o.callback(o.issuer.issueCode("carol-sub", "carol@example.com", nonce), state, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("callback in another browser: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A cookie from another flow does not match either
+	_, _, cookies := o.start(t)
+	w = o.callback(o.issuer.issueCode("carol-sub", "carol@example.com", nonce), state, cookies)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("cookie of another flow: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOAuthCallbackRejectsReusedState(t *testing.T) {
+	o := setupOAuthTest(t)
+
+	state, nonce, cookies := o.start(t)
+	if w := o.callback(o.issuer.issueCode("dave-sub", "dave@example.com", nonce), state, cookies); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w := o.callback(o.issuer.issueCode("dave-sub", "dave@example.com", nonce), state, cookies)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("reused state: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	o := setupOAuthTest(t)
+	erin := createTestUser(t, "erin", "erin@example.com")
+	frank := createTestUser(t, "frank", "frank@example.com")
+
+	state, nonce, cookies := o.link(t, erin.ID)
+	w := o.callback(o.issuer.issueCode("erin-sub", "erin@corp.example.com", nonce), state, cookies)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("link: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Linking the same provider account again to the same user is a no-op
+	state, nonce, cookies = o.link(t, erin.ID)
+	w = o.callback(o.issuer.issueCode("erin-sub", "erin@corp.example.com", nonce), state, cookies)
+	if w.Code != http.StatusOK {
+		t.Fatalf("relink: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Another user cannot take over an identity that is already linked
+	state, nonce, cookies = o.link(t, frank.ID)
+	w = o.callback(o.issuer.issueCode("erin-sub", "erin@corp.example.com", nonce), state, cookies)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("link to another user: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var identities []models.UserIdentity
+	models.DB.Find(&identities)
+	if len(identities) != 1 || identities[0].UserID != erin.ID {
+		t.Fatalf("expected a single identity linked to erin, got %+v", identities)
+	}
+
+	// Logging in with the linked identity logs in as erin
+	state, nonce, cookies = o.start(t)
+	w = o.callback(o.issuer.issueCode("erin-sub", "erin@corp.example.com", nonce), state, cookies)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login with linked identity: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	models.DB.Model(&models.User{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected no new account, got %d accounts", count)
+	}
+}
+
+func TestUnlinkIdentity(t *testing.T) {
+	o := setupOAuthTest(t)
+	grace := createTestUser(t, "grace", "grace@example.com")
+	heidi := createTestUser(t, "heidi", "heidi@example.com")
+
+	identity := models.UserIdentity{UserID: grace.ID, Provider: o.provider, Subject: "grace-sub", Email: "grace@example.com"}
+	if err := models.DB.Create(&identity).Error; err != nil {
+		t.Fatalf("failed to create identity: %v", err)
+	}
+	target := fmt.Sprintf("/users/identities/%d", identity.ID)
+
+	if w := o.do(http.MethodDelete, target, heidi.ID, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unlink by another user: expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := o.do(http.MethodDelete, target, grace.ID, nil); w.Code != http.StatusOK {
+		t.Fatalf("unlink: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := o.do(http.MethodDelete, target, grace.ID, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unlink twice: expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOAuthLoginOfDisabledAccountKeepsAccountState(t *testing.T) {
+	o := setupOAuthTest(t)
+	ivan := createTestUser(t, "ivan", "ivan@example.com")
+
+	deleteAt := time.Now().Add(24 * time.Hour)
+	lockedUntil := time.Now().Add(-time.Minute)
+	if err := models.DB.Model(&ivan).Updates(map[string]interface{}{
+		"status":                models.UserStatusDisabled,
+		"deletion_scheduled_at": deleteAt,
+		"failed_login_attempts": 3,
+		"locked_until":          lockedUntil,
+	}).Error; err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	identity := models.UserIdentity{UserID: ivan.ID, Provider: o.provider, Subject: "ivan-sub", Email: ivan.Email}
+	if err := models.DB.Create(&identity).Error; err != nil {
+		t.Fatalf("failed to create identity: %v", err)
+	}
+
+	state, nonce, cookies := o.start(t)
+	w := o.callback(o.issuer.issueCode("ivan-sub", ivan.Email, nonce), state, cookies)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var user models.User
+	models.DB.First(&user, ivan.ID)
+	if user.DeletionScheduledAt == nil || user.FailedLoginAttempts != 3 || user.LockedUntil == nil {
+		t.Fatalf("a refused login must not cancel the deletion or clear the lockout: %+v", user)
+	}
+
+	var successes int64
+	models.DB.Model(&models.LoginAttempt{}).Where("user_id = ? AND success = ?", ivan.ID, true).Count(&successes)
+	if successes != 0 {
+		t.Fatalf("expected no successful login attempt, got %d", successes)
+	}
+}
@@ -0,0 +1,96 @@
+// controllers/pagination.go
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Pagination describes the page returned by a list endpoint
+type Pagination struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// parsePagination reads the "page" and "page_size" query parameters.
+// It writes a 400 response itself when they are invalid, so callers only need to return when ok is false.
+func parsePagination(c *gin.Context) (Pagination, bool) {
+	p := Pagination{Page: 1, PageSize: defaultPageSize}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid page")
+			return p, false
+		}
+		p.Page = page
+	}
+
+	if value := c.Query("page_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > maxPageSize {
+			utils.ErrorResponse(c, http.StatusBadRequest, "page_size must be between 1 and "+strconv.Itoa(maxPageSize))
+			return p, false
+		}
+		p.PageSize = size
+	}
+
+	return p, true
+}
+
+// paginate counts the rows matched by query, fills in the totals of p and returns the query
+// limited to the requested page
+func paginate(query *gorm.DB, p *Pagination) (*gorm.DB, error) {
+	if err := query.Count(&p.Total).Error; err != nil {
+		return nil, err
+	}
+	p.TotalPages = int((p.Total + int64(p.PageSize) - 1) / int64(p.PageSize))
+
+	return query.Offset((p.Page - 1) * p.PageSize).Limit(p.PageSize), nil
+}
+
+// parseSort reads the "sort" query parameter, a field name that is prefixed with "-" for descending order.
+// columns maps the accepted field names to their column. It writes a 400 response itself when the field
+// is not accepted, so callers only need to return when ok is false.
+func parseSort(c *gin.Context, columns map[string]string, defaultSort string) (string, bool) {
+	value := c.DefaultQuery("sort", defaultSort)
+	direction := "asc"
+	if strings.HasPrefix(value, "-") {
+		direction = "desc"
+	}
+
+	column, ok := columns[strings.TrimPrefix(value, "-")]
+	if !ok {
+		fields := make([]string, 0, len(columns))
+		for field := range columns {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		utils.ErrorResponseWithDetails(c, http.StatusBadRequest, "Invalid sort field", fields)
+		return "", false
+	}
+	return column + " " + direction, true
+}
+
+// likePattern turns user input into a LIKE pattern that matches it literally, with % and _ escaped.
+// prefix only matches values starting with the input, otherwise the input may appear anywhere.
+func likePattern(input string, prefix bool) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(input)
+	if prefix {
+		return escaped + "%"
+	}
+	return "%" + escaped + "%"
+}
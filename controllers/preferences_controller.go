@@ -0,0 +1,132 @@
+// controllers/preferences_controller.go
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"golang.org/x/text/language"
+	"gorm.io/gorm/clause"
+)
+
+// UpdatePreferencesRequest represents the request structure for updating user preferences.
+// Fields that are left out keep their current value.
+type UpdatePreferencesRequest struct {
+	Timezone           *string `json:"timezone"`
+	Locale             *string `json:"locale"`
+	DateFormat         *string `json:"date_format"`
+	DefaultProjectID   *uint   `json:"default_project_id"` // 0 clears the default project
+	EmailNotifications *bool   `json:"email_notifications"`
+}
+
+// GetPreferences handles retrieving the preferences of the current user
+func GetPreferences(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := models.GetUserPreferences(user.ID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to retrieve preferences: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, prefs)
+}
+
+// UpdatePreferences handles changing the preferences of the current user
+func UpdatePreferences(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Timezone == nil && req.Locale == nil && req.DateFormat == nil && req.DefaultProjectID == nil && req.EmailNotifications == nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No fields to update")
+		return
+	}
+
+	prefs, err := models.GetUserPreferences(user.ID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to retrieve preferences: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update preferences")
+		return
+	}
+
+	if req.Timezone != nil {
+		// LoadLocation also accepts "" and "Local", which would mean the server's zone
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Timezone must be an IANA time zone name such as Asia/Jakarta")
+			return
+		}
+		prefs.Timezone = *req.Timezone
+	}
+
+	if req.Locale != nil {
+		tag, err := language.Parse(*req.Locale)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Locale must be a language tag such as en or id-ID")
+			return
+		}
+		prefs.Locale = tag.String()
+	}
+
+	if req.DateFormat != nil {
+		if _, ok := models.DateFormats[*req.DateFormat]; !ok {
+			formats := make([]string, 0, len(models.DateFormats))
+			for format := range models.DateFormats {
+				formats = append(formats, format)
+			}
+			sort.Strings(formats)
+			utils.ErrorResponseWithDetails(c, http.StatusBadRequest, "Invalid date format", formats)
+			return
+		}
+		prefs.DateFormat = *req.DateFormat
+	}
+
+	if req.DefaultProjectID != nil {
+		if *req.DefaultProjectID == 0 {
+			prefs.DefaultProjectID = nil
+		} else {
+			hasAccess, err := models.UserHasAccessToProject(user.ID, *req.DefaultProjectID)
+			if err != nil {
+				utils.Logger.Errorf("Failed to check project access: %v", err)
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update preferences")
+				return
+			}
+			if !hasAccess {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Default project not found")
+				return
+			}
+			prefs.DefaultProjectID = req.DefaultProjectID
+		}
+	}
+
+	if req.EmailNotifications != nil {
+		prefs.EmailNotifications = *req.EmailNotifications
+	}
+
+	// Users without a saved row still have the defaults, so the first update inserts it
+	prefs.UpdatedAt = time.Now()
+	if err := models.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&prefs).Error; err != nil {
+		utils.Logger.Errorf("Failed to save preferences: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update preferences")
+		return
+	}
+
+	utils.Logger.Infof("Preferences updated: UserID %d", user.ID)
+
+	utils.SuccessResponse(c, prefs)
+}
@@ -32,6 +32,11 @@ type UpdateProjectRequest struct {
 	TeamIDs     []uint     `json:"team_ids"` // Optional: IDs of teams to associate with the project
 }
 
+// TransferProjectRequest represents the request structure for handing a project to a new owner
+type TransferProjectRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
 // CreateProject handles the creation of a new project
 func CreateProject(c *gin.Context) {
 	// Retrieve the User object from context set by AuthMiddleware
@@ -117,7 +122,7 @@ func CreateProject(c *gin.Context) {
 		"title":       project.Title,
 		"description": project.Description,
 		"priority":    project.Priority,
-		"deadline":    project.Deadline,
+		"deadline":    inUserZone(c, project.Deadline),
 		"status":      project.Status,
 		"owner_id":    project.OwnerID,
 		"created_at":  project.CreatedAt,
@@ -155,11 +160,9 @@ func ListProjects(c *gin.Context) {
 	}
 
 	var collaboratingProjects []models.Project
-	// Fetch projects associated with teams the user is a member of
+	// Fetch projects associated with teams the user is a member of, directly or through a sub-team
 	if err := models.DB.
-		Joins("JOIN project_teams ON project_teams.project_id = projects.id").
-		Joins("JOIN team_members ON team_members.team_id = project_teams.team_id").
-		Where("team_members.user_id = ?", user.ID).
+		Where("id IN (?)", models.ProjectIDsOfUserTeams(user.ID)).
 		Preload("Teams").
 		Preload("Owner").
 		Find(&collaboratingProjects).Error; err != nil {
@@ -168,12 +171,24 @@ func ListProjects(c *gin.Context) {
 		return
 	}
 
-	// Combine owned and collaborating projects, avoiding duplicates
+	var sharedProjects []models.Project
+	// Fetch projects the user was added to as a collaborator
+	if err := models.DB.
+		Joins("JOIN collaborations ON collaborations.project_id = projects.id").
+		Where("collaborations.user_id = ?", user.ID).
+		Preload("Teams").
+		Find(&sharedProjects).Error; err != nil {
+		utils.Logger.Errorf("Failed to retrieve shared projects: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve projects")
+		return
+	}
+
+	// Combine owned, collaborating and shared projects, avoiding duplicates
 	projectMap := make(map[uint]models.Project)
 	for _, project := range ownedProjects {
 		projectMap[project.ID] = project
 	}
-	for _, project := range collaboratingProjects {
+	for _, project := range append(collaboratingProjects, sharedProjects...) {
 		if _, exists := projectMap[project.ID]; !exists {
 			projectMap[project.ID] = project
 		}
@@ -193,7 +208,7 @@ func ListProjects(c *gin.Context) {
 			"title":       project.Title,
 			"description": project.Description,
 			"priority":    project.Priority,
-			"deadline":    project.Deadline,
+			"deadline":    inUserZone(c, project.Deadline),
 			"status":      project.Status,
 			"owner_id":    project.OwnerID,
 			"created_at":  project.CreatedAt,
@@ -208,21 +223,6 @@ func ListProjects(c *gin.Context) {
 
 // GetProject handles retrieving a single project by ID
 func GetProject(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-
 	// Retrieve project_id from URL parameters
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -243,31 +243,13 @@ func GetProject(c *gin.Context) {
 		return
 	}
 
-	// Check if the user is the owner
-	if project.OwnerID != user.ID {
-		// Check if the user is a member of any team associated with the project
-		var count int64
-		err = models.DB.Table("team_members").
-			Where("team_id IN (SELECT team_id FROM project_teams WHERE project_id = ?) AND user_id = ?", project.ID, user.ID).
-			Count(&count).Error
-		if err != nil {
-			utils.Logger.Errorf("Failed to check team membership: %v", err)
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve project")
-			return
-		}
-		if count == 0 && user.Role != "admin" {
-			utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-			return
-		}
-	}
-
 	// Prepare response data
 	responseData := gin.H{
 		"id":          project.ID,
 		"title":       project.Title,
 		"description": project.Description,
 		"priority":    project.Priority,
-		"deadline":    project.Deadline,
+		"deadline":    inUserZone(c, project.Deadline),
 		"status":      project.Status,
 		"owner_id":    project.OwnerID,
 		"created_at":  project.CreatedAt,
@@ -280,21 +262,6 @@ func GetProject(c *gin.Context) {
 
 // UpdateProject handles updating a project's details
 func UpdateProject(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-
 	// Retrieve project_id from URL parameters
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -322,9 +289,9 @@ func UpdateProject(c *gin.Context) {
 		return
 	}
 
-	// Check if the user is the owner
-	if project.OwnerID != user.ID {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have permission to update this project")
+	// Changing the teams working on the project requires the project.manage_teams permission
+	if req.TeamIDs != nil && !hasPermission(c, models.PermissionProjectManageTeams) {
+		utils.ForbiddenResponse(c, string(models.PermissionProjectManageTeams))
 		return
 	}
 
@@ -398,7 +365,7 @@ func UpdateProject(c *gin.Context) {
 		"title":       project.Title,
 		"description": project.Description,
 		"priority":    project.Priority,
-		"deadline":    project.Deadline,
+		"deadline":    inUserZone(c, project.Deadline),
 		"status":      project.Status,
 		"owner_id":    project.OwnerID,
 		"created_at":  project.CreatedAt,
@@ -411,21 +378,6 @@ func UpdateProject(c *gin.Context) {
 
 // DeleteProject handles deleting a project
 func DeleteProject(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-
 	// Retrieve project_id from URL parameters
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.Atoi(projectIDParam)
@@ -446,12 +398,6 @@ func DeleteProject(c *gin.Context) {
 		return
 	}
 
-	// Check if the user is the owner
-	if project.OwnerID != user.ID {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have permission to delete this project")
-		return
-	}
-
 	// Delete the project (soft delete if using GORM's DeletedAt)
 	if err := models.DB.Delete(&project).Error; err != nil {
 		utils.Logger.Errorf("Failed to delete project: %v", err)
@@ -465,4 +411,110 @@ func DeleteProject(c *gin.Context) {
 	utils.SuccessResponse(c, gin.H{"message": "Project deleted successfully"})
 }
 
+// TransferProject handles handing the ownership of a project to another user.
+// The previous owner stays on the project as an admin collaborator.
+func TransferProject(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	// Retrieve project_id from URL parameters
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+
+	var req TransferProjectRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var project models.Project
+	if err := models.DB.First(&project, projectID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Project not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to retrieve project for transfer: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to transfer project")
+		return
+	}
+
+	if req.UserID == project.OwnerID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "User already owns this project")
+		return
+	}
 
+	var newOwner models.User
+	if err := models.DB.First(&newOwner, req.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to retrieve new project owner: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to transfer project")
+		return
+	}
+	if newOwner.IsDisabled() || newOwner.DeletionScheduledAt != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Projects cannot be transferred to this user")
+		return
+	}
+
+	// Like team transfers, the new owner must already work on the project
+	isCollaborator, err := models.UserIsProjectCollaborator(newOwner.ID, project.ID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to check project collaboration: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to transfer project")
+		return
+	}
+	if !isCollaborator {
+		isTeamMember, err := models.UserIsMemberOfProjectTeams(newOwner.ID, project.ID)
+		if err != nil {
+			utils.Logger.Errorf("Failed to check project team membership: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to transfer project")
+			return
+		}
+		if !isTeamMember {
+			utils.ErrorResponse(c, http.StatusBadRequest, "The new owner must be a collaborator or a member of one of the project's teams")
+			return
+		}
+	}
+
+	previousOwnerID := project.OwnerID
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&project).Update("owner_id", newOwner.ID).Error; err != nil {
+			return err
+		}
+
+		// The new owner no longer needs a collaboration, the previous owner gets one instead
+		if err := tx.Where("project_id = ? AND user_id IN ?", project.ID, []uint{newOwner.ID, previousOwnerID}).
+			Delete(&models.Collaboration{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Collaboration{
+			ProjectID: project.ID,
+			UserID:    previousOwnerID,
+			Role:      models.CollaborationRoleAdmin,
+		}).Error
+	})
+	if err != nil {
+		utils.Logger.Errorf("Failed to transfer project: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to transfer project")
+		return
+	}
+	project.OwnerID = newOwner.ID
+
+	utils.Logger.Infof("Project transferred: ProjectID %d from UserID %d to UserID %d by UserID %d",
+		project.ID, previousOwnerID, newOwner.ID, user.ID)
+
+	utils.SuccessResponse(c, gin.H{
+		"id":                project.ID,
+		"title":             project.Title,
+		"owner_id":          project.OwnerID,
+		"previous_owner_id": previousOwnerID,
+	})
+}
@@ -13,6 +13,12 @@ import (
 
 // AddProjectTeam handles adding a team to a project
 func AddProjectTeam(c *gin.Context) {
+	// Ambil pengguna dari konteks yang di-set oleh AuthMiddleware
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
 	// Ambil project_id dari parameter URL
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.ParseUint(projectIDParam, 10, 64)
@@ -21,26 +27,6 @@ func AddProjectTeam(c *gin.Context) {
 		return
 	}
 
-	// Ambil user_id dari konteks
-	currentUserID, exists := c.Get("user_id")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "User ID not found")
-		return
-	}
-	userID := currentUserID.(uint)
-
-	// Cek apakah pengguna adalah pemilik proyek
-	isOwner, err := models.UserIsProjectOwner(userID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project ownership: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to add team to project")
-		return
-	}
-	if !isOwner {
-		utils.ErrorResponse(c, http.StatusForbidden, "Only the project owner can add teams to the project")
-		return
-	}
-
 	// Bind JSON request untuk mendapatkan team_id
 	var req struct {
 		TeamID uint `json:"team_id" binding:"required"`
@@ -89,7 +75,7 @@ func AddProjectTeam(c *gin.Context) {
 		return
 	}
 
-	utils.Logger.Infof("Team ID %d added to Project ID %d by User ID %d", team.ID, project.ID, userID)
+	utils.Logger.Infof("Team ID %d added to Project ID %d by User ID %d", team.ID, project.ID, user.ID)
 	utils.SuccessResponse(c, gin.H{"message": "Team added to project successfully"})
 }
 
@@ -103,26 +89,6 @@ func ListProjectTeams(c *gin.Context) {
 		return
 	}
 
-	// Ambil user_id dari konteks
-	currentUserID, exists := c.Get("user_id")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "User ID not found")
-		return
-	}
-	userID := currentUserID.(uint)
-
-	// Cek apakah pengguna memiliki akses ke proyek
-	hasAccess, err := models.UserHasAccessToProject(userID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list project teams")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	// Ambil proyek beserta tim-tim yang terkait
 	var project models.Project
 	if err := models.DB.Preload("Teams").First(&project, uint(projectID)).Error; err != nil {
@@ -140,6 +106,12 @@ func ListProjectTeams(c *gin.Context) {
 
 // RemoveProjectTeam handles removing a team from a project
 func RemoveProjectTeam(c *gin.Context) {
+	// Ambil pengguna dari konteks yang di-set oleh AuthMiddleware
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
 	// Ambil project_id dan team_id dari parameter URL
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.ParseUint(projectIDParam, 10, 64)
@@ -155,26 +127,6 @@ func RemoveProjectTeam(c *gin.Context) {
 		return
 	}
 
-	// Ambil user_id dari konteks
-	currentUserID, exists := c.Get("user_id")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "User ID not found")
-		return
-	}
-	userID := currentUserID.(uint)
-
-	// Cek apakah pengguna adalah pemilik proyek
-	isOwner, err := models.UserIsProjectOwner(userID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project ownership: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove team from project")
-		return
-	}
-	if !isOwner {
-		utils.ErrorResponse(c, http.StatusForbidden, "Only the project owner can remove teams from the project")
-		return
-	}
-
 	// Ambil proyek dari database
 	var project models.Project
 	if err := models.DB.Preload("Teams").First(&project, uint(projectID)).Error; err != nil {
@@ -219,6 +171,6 @@ func RemoveProjectTeam(c *gin.Context) {
 		return
 	}
 
-	utils.Logger.Infof("Team ID %d removed from Project ID %d by User ID %d", team.ID, project.ID, userID)
+	utils.Logger.Infof("Team ID %d removed from Project ID %d by User ID %d", team.ID, project.ID, user.ID)
 	utils.SuccessResponse(c, gin.H{"message": "Team removed from project successfully"})
 }
@@ -0,0 +1,77 @@
+// controllers/session_controller.go
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+)
+
+// ListSessions handles listing the active sessions (devices) of the current user
+func ListSessions(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var sessions []models.Session
+	if err := models.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error; err != nil {
+		utils.Logger.Errorf("Failed to retrieve sessions: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve sessions")
+		return
+	}
+
+	currentSessionID := c.GetString(utils.ContextSessionKey)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	utils.SuccessResponse(c, sessions)
+}
+
+// RevokeSession handles logging out a single session of the current user
+func RevokeSession(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Param("session_id")
+	if err := models.RevokeSession(user.ID, sessionID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to revoke session: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+
+	utils.Logger.Infof("Session %s revoked by user ID: %d", sessionID, user.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeAllSessions handles logging the current user out everywhere, including this session
+func RevokeAllSessions(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := models.RevokeUserSessions(user.ID); err != nil {
+		utils.Logger.Errorf("Failed to revoke sessions: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+
+	utils.Logger.Infof("All sessions revoked for user ID: %d", user.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "Logged out from all sessions"})
+}
@@ -57,7 +57,7 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
-	// Check if the project exists and the user has access
+	// Check if the project exists
 	var project models.Project
 	if err := models.DB.Preload("Teams.Members").First(&project, uint(projectID)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -69,18 +69,6 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create task")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var req CreateTaskRequest
 	// Bind JSON request to struct
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -88,6 +76,12 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
+	// Assigning the task to someone else requires the task.assign permission
+	if req.AssignedToID != nil && *req.AssignedToID != user.ID && !hasPermission(c, models.PermissionTaskAssign) {
+		utils.ForbiddenResponse(c, string(models.PermissionTaskAssign))
+		return
+	}
+
 	// If AssignedToID is provided, check if the assigned user exists and has access
 	if req.AssignedToID != nil {
 		var assignedUser models.User
@@ -140,7 +134,7 @@ func CreateTask(c *gin.Context) {
 		"description":  task.Description,
 		"priority":     task.Priority,
 		"status":       task.Status,
-		"deadline":     task.Deadline,
+		"deadline":     inUserZone(c, task.Deadline),
 		"assigned_to":  task.AssignedToID,
 		"project_id":   task.ProjectID,
 		"created_at":   task.CreatedAt,
@@ -151,23 +145,35 @@ func CreateTask(c *gin.Context) {
 	utils.CreatedResponse(c, responseData)
 }
 
-// ListTasks handles retrieving all tasks within a specific project
-func ListTasks(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
+// taskDueFilters lists the values accepted by the due query parameter of ListTasks
+var taskDueFilters = []string{"overdue", "today", "tomorrow", "week"}
 
-	user, ok := userInterface.(models.User)
+// filterTasksByDue limits query to the open tasks of a deadline bucket. Days start at midnight in the
+// time zone of the current user, so "today" means the user's today rather than the server's.
+func filterTasksByDue(c *gin.Context, query *gorm.DB, due string) (*gorm.DB, bool) {
+	user, ok := c.Value(utils.ContextUserKey).(models.User)
 	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
+		return query, false
+	}
+
+	now := time.Now()
+	today := userPreferences(c, user.ID).StartOfDay(now)
+	switch due {
+	case "overdue":
+		return models.TasksOverdue(query, now), true
+	case "today":
+		return models.TasksDueBetween(query, today, today.AddDate(0, 0, 1)), true
+	case "tomorrow":
+		return models.TasksDueBetween(query, today.AddDate(0, 0, 1), today.AddDate(0, 0, 2)), true
+	case "week":
+		return models.TasksDueBetween(query, today, today.AddDate(0, 0, 7)), true
+	}
+	return query, false
+}
 
+// ListTasks handles retrieving all tasks within a specific project.
+// The optional due parameter (overdue, today, tomorrow or week) returns only open tasks in that deadline bucket.
+func ListTasks(c *gin.Context) {
 	// Retrieve project_id from URL parameters
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.ParseUint(projectIDParam, 10, 64)
@@ -176,21 +182,18 @@ func ListTasks(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve tasks")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
+	query := models.DB.Where("project_id = ?", uint(projectID))
+	if due := c.Query("due"); due != "" {
+		var ok bool
+		if query, ok = filterTasksByDue(c, query, due); !ok {
+			utils.ErrorResponseWithDetails(c, http.StatusBadRequest, "Invalid due filter", taskDueFilters)
+			return
+		}
 	}
 
 	var tasks []models.Task
 	// Retrieve all tasks for the project, including AssignedTo user
-	if err := models.DB.Where("project_id = ?", uint(projectID)).
+	if err := query.
 		Preload("AssignedTo").
 		Order("created_at desc").
 		Find(&tasks).Error; err != nil {
@@ -208,7 +211,7 @@ func ListTasks(c *gin.Context) {
 			"description":   task.Description,
 			"priority":      task.Priority,
 			"status":        task.Status,
-			"deadline":      task.Deadline,
+			"deadline":      inUserZone(c, task.Deadline),
 			"assigned_to_id": task.AssignedToID,
 			"project_id":    task.ProjectID,
 			"created_at":    task.CreatedAt,
@@ -221,21 +224,6 @@ func ListTasks(c *gin.Context) {
 
 // GetTask handles retrieving a specific task within a project
 func GetTask(c *gin.Context) {
-	// Retrieve the User object from context set by AuthMiddleware
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-
 	// Retrieve project_id and task_id from URL parameters
 	projectIDParam := c.Param("project_id")
 	projectID, err := strconv.ParseUint(projectIDParam, 10, 64)
@@ -251,18 +239,6 @@ func GetTask(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve task")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var task models.Task
 	// Retrieve the task from the database
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(taskID), uint(projectID)).
@@ -284,7 +260,7 @@ func GetTask(c *gin.Context) {
 		"description":   task.Description,
 		"priority":      task.Priority,
 		"status":        task.Status,
-		"deadline":      task.Deadline,
+		"deadline":      inUserZone(c, task.Deadline),
 		"assigned_to_id": task.AssignedToID,
 		"project_id":    task.ProjectID,
 		"created_at":    task.CreatedAt,
@@ -339,18 +315,6 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update task")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var task models.Task
 	// Retrieve the task from the database
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(taskID), uint(projectID)).
@@ -364,6 +328,16 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
+	// Without the task.assign permission users may only take unassigned tasks or give up their own
+	if req.AssignedToID != nil && !hasPermission(c, models.PermissionTaskAssign) {
+		fromSelf := task.AssignedToID == nil || *task.AssignedToID == user.ID
+		toSelf := *req.AssignedToID == 0 || *req.AssignedToID == user.ID
+		if !fromSelf || !toSelf {
+			utils.ForbiddenResponse(c, string(models.PermissionTaskAssign))
+			return
+		}
+	}
+
 	// If AssignedToID is provided, check if the assigned user exists and has access
 	if req.AssignedToID != nil {
 		if *req.AssignedToID != 0 {
@@ -435,7 +409,7 @@ func UpdateTask(c *gin.Context) {
 		"description":   task.Description,
 		"priority":      task.Priority,
 		"status":        task.Status,
-		"deadline":      task.Deadline,
+		"deadline":      inUserZone(c, task.Deadline),
 		"assigned_to_id": task.AssignedToID,
 		"project_id":    task.ProjectID,
 		"created_at":    task.CreatedAt,
@@ -478,18 +452,6 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
-	// Check if the user has access to the project
-	hasAccess, err := models.UserHasAccessToProject(user.ID, uint(projectID))
-	if err != nil {
-		utils.Logger.Errorf("Failed to check project access: %v", err)
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete task")
-		return
-	}
-	if !hasAccess {
-		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this project")
-		return
-	}
-
 	var task models.Task
 	// Retrieve the task from the database
 	if err := models.DB.Where("id = ? AND project_id = ?", uint(taskID), uint(projectID)).
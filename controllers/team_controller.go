@@ -4,10 +4,12 @@ package controllers
 import (
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
 	"gorm.io/gorm"
 )
 
@@ -26,6 +28,33 @@ type TeamUpdateInput struct {
     Description string `json:"description"`
 }
 
+// TeamTransferInput defines the input structure for handing a team to a new owner
+type TeamTransferInput struct {
+    UserID uint `json:"user_id" binding:"required"`
+}
+
+// TeamParentInput defines the input structure for moving a team below another team
+type TeamParentInput struct {
+    ParentID *uint `json:"parent_id"` // Null or 0 makes the team a top-level team
+}
+
+// TeamMemberInput defines the input structure for adding a member to a team
+type TeamMemberInput struct {
+    UserID uint            `json:"user_id" binding:"required"`
+    Role   models.TeamRole `json:"role" binding:"omitempty,oneof=maintainer member"`
+}
+
+// TeamMemberRoleInput defines the input structure for changing the role of a team member
+type TeamMemberRoleInput struct {
+    Role models.TeamRole `json:"role" binding:"required,oneof=maintainer member"`
+}
+
+// TeamDetail is the response of GetTeam. Members are listed separately through ListTeamMembers.
+type TeamDetail struct {
+    models.Team
+    MemberCount int64 `json:"member_count"`
+}
+
 // CreateTeam handles POST /teams/
 func CreateTeam(c *gin.Context) {
     var input TeamInput
@@ -67,8 +96,14 @@ func CreateTeam(c *gin.Context) {
         Owner:       user, // Ensure user has Username, Email, Role
     }
 
-    // Save team to the database
-    if err := models.DB.Create(&newTeam).Error; err != nil {
+    // Save team to the database, with the owner as its first member
+    err := models.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(&newTeam).Error; err != nil {
+            return err
+        }
+        return models.SetTeamMemberRole(tx, newTeam.ID, user.ID, models.TeamRoleOwner)
+    })
+    if err != nil {
         log.Printf("Error creating team: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
         return
@@ -84,18 +119,58 @@ func CreateTeam(c *gin.Context) {
     c.JSON(http.StatusCreated, newTeam)
 }
 
+// teamSortColumns lists the fields ListTeams can be sorted by
+var teamSortColumns = map[string]string{
+    "name":       "LOWER(name)",
+    "created_at": "created_at",
+    "updated_at": "updated_at",
+}
+
 // ListTeams handles GET /teams/
+// It returns the teams the caller is a member of (every team for admins), filtered by q on the name,
+// sorted by sort (name, created_at or updated_at, "-" prefix for descending) and paginated.
+// Members are not included; they are listed by ListTeamMembers.
 func ListTeams(c *gin.Context) {
-    var teams []models.Team
+    user, ok := currentUser(c)
+    if !ok {
+        return
+    }
+
+    pagination, ok := parsePagination(c)
+    if !ok {
+        return
+    }
+    order, ok := parseSort(c, teamSortColumns, "name")
+    if !ok {
+        return
+    }
+
+    query := models.DB.Model(&models.Team{}).Where("deleted_at IS NULL")
+    if user.Role != models.RoleAdmin {
+        query = query.Where("owner_id = ? OR id IN (?)", user.ID, models.TeamIDsOfUser(user.ID))
+    }
+    if q := strings.TrimSpace(c.Query("q")); q != "" {
+        query = query.Where("LOWER(name) LIKE ?", likePattern(strings.ToLower(q), false))
+    }
+
+    page, err := paginate(query, &pagination)
+    if err != nil {
+        log.Printf("Error counting teams: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve teams"})
+        return
+    }
 
-    // Retrieve all teams from the database and preload Owner and Members
-    if err := models.DB.Preload("Owner").Preload("Members").Find(&teams).Error; err != nil {
+    teams := []models.Team{}
+    if err := page.Preload("Owner").Order(order).Order("id").Find(&teams).Error; err != nil {
         log.Printf("Error listing teams: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve teams"})
         return
     }
 
-    c.JSON(http.StatusOK, teams)
+    c.JSON(http.StatusOK, gin.H{
+        "teams":      teams,
+        "pagination": pagination,
+    })
 }
 
 // GetTeam handles GET /teams/:team_id
@@ -103,8 +178,8 @@ func GetTeam(c *gin.Context) {
     teamID := c.Param("team_id")
     var team models.Team
 
-    // Find team by ID and preload Owner and Members
-    if err := models.DB.Preload("Owner").Preload("Members").First(&team, teamID).Error; err != nil {
+    // Find team by ID and preload Owner and the direct sub-teams. Members are paged through ListTeamMembers.
+    if err := models.DB.Preload("Owner").Preload("SubTeams.Owner").First(&team, teamID).Error; err != nil {
         if err == gorm.ErrRecordNotFound {
             c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
         } else {
@@ -114,7 +189,14 @@ func GetTeam(c *gin.Context) {
         return
     }
 
-    c.JSON(http.StatusOK, team)
+    var memberCount int64
+    if err := models.DB.Model(&models.TeamMembership{}).Where("team_id = ?", team.ID).Count(&memberCount).Error; err != nil {
+        log.Printf("Error counting team members: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusOK, TeamDetail{Team: team, MemberCount: memberCount})
 }
 
 // UpdateTeam handles PUT /teams/:team_id
@@ -153,8 +235,8 @@ func UpdateTeam(c *gin.Context) {
     team.Name = input.Name
     team.Description = input.Description
 
-    // Save changes to the database
-    if err := models.DB.Save(&team).Error; err != nil {
+    // Save changes to the database. Only the edited columns are written, so a concurrent SetTeamParent is not undone.
+    if err := models.DB.Model(&team).Updates(map[string]interface{}{"name": team.Name, "description": team.Description}).Error; err != nil {
         log.Printf("Error updating team: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
         return
@@ -212,35 +294,21 @@ func AddTeamMember(c *gin.Context) {
         return
     }
 
-    // Retrieve User object from context
-    userInterface, exists := c.Get("user")
-    if !exists {
-        log.Printf("User not found in context")
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-        return
-    }
+    var input TeamMemberInput
 
-    user, ok := userInterface.(models.User)
-    if !ok {
-        log.Printf("User type assertion failed")
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-        return
-    }
-
-    // Authorization: Only team owner or admin can add members
-    if team.OwnerID != user.ID && user.Role != "admin" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+    // Bind JSON input to TeamMemberInput struct
+    if err := c.ShouldBindJSON(&input); err != nil {
+        log.Printf("Error binding JSON: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    var input struct {
-        UserID uint `json:"user_id" binding:"required"`
+    // Only the owner may add maintainers
+    if input.Role == "" {
+        input.Role = models.TeamRoleMember
     }
-
-    // Bind JSON input to struct input
-    if err := c.ShouldBindJSON(&input); err != nil {
-        log.Printf("Error binding JSON: %v", err)
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    if input.Role == models.TeamRoleMaintainer && !hasPermission(c, models.PermissionTeamManageRoles) {
+        utils.ForbiddenResponse(c, string(models.PermissionTeamManageRoles))
         return
     }
 
@@ -273,13 +341,14 @@ func AddTeamMember(c *gin.Context) {
     }
 
     // Add user to team members
-    if err := models.DB.Model(&team).Association("Members").Append(&member); err != nil {
+    membership := models.TeamMembership{TeamID: team.ID, UserID: member.ID, Role: input.Role}
+    if err := models.DB.Create(&membership).Error; err != nil {
         log.Printf("Error adding member to team: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "User added to team successfully"})
+    c.JSON(http.StatusOK, gin.H{"message": "User added to team successfully", "role": membership.Role})
 }
 
 // ListTeamMembers handles GET /teams/:team_id/members/
@@ -287,18 +356,41 @@ func ListTeamMembers(c *gin.Context) {
     teamID := c.Param("team_id")
     var team models.Team
 
-    // Find team and preload Members
-    if err := models.DB.Preload("Members").First(&team, teamID).Error; err != nil {
+    // Find team by ID
+    if err := models.DB.First(&team, teamID).Error; err != nil {
         if err == gorm.ErrRecordNotFound {
             c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
         } else {
-            log.Printf("Error retrieving team members: %v", err)
+            log.Printf("Error retrieving team: %v", err)
             c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         }
         return
     }
 
-    c.JSON(http.StatusOK, team.Members)
+    pagination, ok := parsePagination(c)
+    if !ok {
+        return
+    }
+
+    page, err := paginate(models.DB.Model(&models.TeamMembership{}).Where("team_id = ?", team.ID), &pagination)
+    if err != nil {
+        log.Printf("Error counting team members: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+        return
+    }
+
+    // Retrieve the memberships with their users, so every member is listed with its role
+    memberships := []models.TeamMembership{}
+    if err := page.Preload("User").Order("joined_at").Order("user_id").Find(&memberships).Error; err != nil {
+        log.Printf("Error retrieving team members: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve team members"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "members":    memberships,
+        "pagination": pagination,
+    })
 }
 
 // RemoveTeamMember handles DELETE /teams/:team_id/members/:user_id
@@ -320,24 +412,210 @@ func RemoveTeamMember(c *gin.Context) {
         return
     }
 
-    // Retrieve User object from context
-    authUserInterface, exists := c.Get("user")
-    if !exists {
-        log.Printf("User not found in context")
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+    // Find user by ID
+    if err := models.DB.First(&user, userID).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+        } else {
+            log.Printf("Error finding user: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
         return
     }
 
-    authUser, ok := authUserInterface.(models.User)
+    membership, ok := findTeamMembership(c, team.ID, user.ID)
     if !ok {
-        log.Printf("User type assertion failed")
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+        return
+    }
+    if membership.Role == models.TeamRoleOwner {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer ownership before removing the owner"})
+        return
+    }
+
+    // Maintainers may leave on their own, but only the owner may remove other maintainers
+    caller, ok := currentUser(c)
+    if !ok {
+        return
+    }
+    if membership.Role == models.TeamRoleMaintainer && caller.ID != user.ID && !hasPermission(c, models.PermissionTeamManageRoles) {
+        utils.ForbiddenResponse(c, string(models.PermissionTeamManageRoles))
+        return
+    }
+
+    // Remove user from team members
+    if err := models.DB.Where("team_id = ? AND user_id = ?", team.ID, user.ID).Delete(&models.TeamMembership{}).Error; err != nil {
+        log.Printf("Error removing member from team: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "User removed from team successfully"})
+}
+
+// TransferTeam handles POST /teams/:team_id/transfer
+// The new owner must be a member of the team; the previous owner stays on as a maintainer.
+func TransferTeam(c *gin.Context) {
+    teamID := c.Param("team_id")
+    var team models.Team
+
+    // Find team by ID
+    if err := models.DB.First(&team, teamID).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+        } else {
+            log.Printf("Error finding team: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
+        return
+    }
+
+    var input TeamTransferInput
+
+    // Bind JSON input to TeamTransferInput struct
+    if err := c.ShouldBindJSON(&input); err != nil {
+        log.Printf("Error binding JSON: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if input.UserID == team.OwnerID {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "User already owns this team"})
+        return
+    }
+
+    var newOwner models.User
+    if err := models.DB.Joins("JOIN team_members ON team_members.user_id = users.id AND team_members.team_id = ?", team.ID).
+        First(&newOwner, input.UserID).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "The new owner must be a member of the team"})
+        } else {
+            log.Printf("Error finding new owner: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
+        return
+    }
+    if newOwner.IsDisabled() || newOwner.DeletionScheduledAt != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Teams cannot be transferred to this user"})
+        return
+    }
+
+    previousOwnerID := team.OwnerID
+    err := models.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(&team).Update("owner_id", newOwner.ID).Error; err != nil {
+            return err
+        }
+        if err := models.SetTeamMemberRole(tx, team.ID, newOwner.ID, models.TeamRoleOwner); err != nil {
+            return err
+        }
+
+        // Keep the previous owner in the team so they do not lose access to its projects
+        return models.SetTeamMemberRole(tx, team.ID, previousOwnerID, models.TeamRoleMaintainer)
+    })
+    if err != nil {
+        log.Printf("Error transferring team: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer team"})
+        return
+    }
+
+    // Preload Owner to include in the response
+    if err := models.DB.Preload("Owner").First(&team, team.ID).Error; err != nil {
+        log.Printf("Error preloading team: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transferred team"})
+        return
+    }
+
+    c.JSON(http.StatusOK, team)
+}
+
+// SetTeamParent handles PUT /teams/:team_id/parent
+// Members of a sub-team may work on the projects of every team above it, so moving a team below another
+// one also requires permission to update the new parent.
+func SetTeamParent(c *gin.Context) {
+    user, ok := currentUser(c)
+    if !ok {
+        return
+    }
+
+    teamID := c.Param("team_id")
+    var team models.Team
+
+    // Find team by ID
+    if err := models.DB.First(&team, teamID).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+        } else {
+            log.Printf("Error finding team: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
+        return
+    }
+
+    var input TeamParentInput
+
+    // Bind JSON input to TeamParentInput struct
+    if err := c.ShouldBindJSON(&input); err != nil {
+        log.Printf("Error binding JSON: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if input.ParentID != nil && *input.ParentID == 0 {
+        input.ParentID = nil
+    }
+
+    if input.ParentID != nil {
+        permissions, err := models.TeamPermissions(user, *input.ParentID)
+        if err != nil {
+            if err == gorm.ErrRecordNotFound {
+                c.JSON(http.StatusNotFound, gin.H{"error": "Parent team not found"})
+            } else {
+                log.Printf("Error checking parent team permissions: %v", err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            }
+            return
+        }
+        if !permissions.Has(models.PermissionTeamUpdate) {
+            utils.ForbiddenResponse(c, string(models.PermissionTeamUpdate))
+            return
+        }
+    }
+
+    if err := models.SetTeamParent(team.ID, input.ParentID); err != nil {
+        if err == models.ErrTeamCycle {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        log.Printf("Error moving team: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move team"})
         return
     }
 
-    // Authorization: Only team owner or admin can remove members
-    if team.OwnerID != authUser.ID && authUser.Role != "admin" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+    // Preload Owner to include in the response
+    if err := models.DB.Preload("Owner").First(&team, team.ID).Error; err != nil {
+        log.Printf("Error preloading team: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve moved team"})
+        return
+    }
+
+    c.JSON(http.StatusOK, team)
+}
+
+// UpdateTeamMemberRole handles PUT /teams/:team_id/members/:user_id/role
+// Members can be promoted to maintainer and maintainers demoted; the owner role only changes through a transfer.
+func UpdateTeamMemberRole(c *gin.Context) {
+    teamID := c.Param("team_id")
+    userID := c.Param("user_id")
+
+    var team models.Team
+    var user models.User
+
+    // Find team by ID
+    if err := models.DB.First(&team, teamID).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+        } else {
+            log.Printf("Error finding team: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
         return
     }
 
@@ -352,12 +630,45 @@ func RemoveTeamMember(c *gin.Context) {
         return
     }
 
-    // Remove user from team members
-    if err := models.DB.Model(&team).Association("Members").Delete(&user); err != nil {
-        log.Printf("Error removing member from team: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    var input TeamMemberRoleInput
+
+    // Bind JSON input to TeamMemberRoleInput struct
+    if err := c.ShouldBindJSON(&input); err != nil {
+        log.Printf("Error binding JSON: %v", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "User removed from team successfully"})
+    membership, ok := findTeamMembership(c, team.ID, user.ID)
+    if !ok {
+        return
+    }
+    if membership.Role == models.TeamRoleOwner {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Use the transfer endpoint to change the owner"})
+        return
+    }
+
+    if err := models.DB.Model(&membership).Update("role", input.Role).Error; err != nil {
+        log.Printf("Error updating member role: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member role"})
+        return
+    }
+    membership.Role = input.Role
+
+    c.JSON(http.StatusOK, membership)
+}
+
+// findTeamMembership retrieves the membership of a user in a team, writing a 404 response when there is none
+func findTeamMembership(c *gin.Context, teamID, userID uint) (models.TeamMembership, bool) {
+    membership, err := models.GetTeamMembership(teamID, userID)
+    if err != nil {
+        if err == gorm.ErrRecordNotFound {
+            c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of the team"})
+        } else {
+            log.Printf("Error finding team membership: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
+        return models.TeamMembership{}, false
+    }
+    return membership, true
 }
@@ -0,0 +1,415 @@
+// controllers/team_invitation_controller.go
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateTeamInvitationRequest represents the request structure for inviting an email address to a team
+type CreateTeamInvitationRequest struct {
+	Email string          `json:"email" binding:"required,email"`
+	Role  models.TeamRole `json:"role" binding:"omitempty,oneof=maintainer member"` // Default member
+}
+
+// TeamInvitationTokenRequest represents the request structure for answering an invitation with the emailed token
+type TeamInvitationTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CreateTeamInvitation handles POST /teams/:team_id/invitations/
+// The invitee does not need an account yet; the email links to the page where they can sign up and accept.
+func CreateTeamInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	team, ok := findTeam(c)
+	if !ok {
+		return
+	}
+
+	var req CreateTeamInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Only the owner may invite maintainers
+	if req.Role == "" {
+		req.Role = models.TeamRoleMember
+	}
+	if req.Role == models.TeamRoleMaintainer && !hasPermission(c, models.PermissionTeamManageRoles) {
+		utils.ForbiddenResponse(c, string(models.PermissionTeamManageRoles))
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// A registered invitee is looked up to skip existing members and to honour their email preference
+	var invitee models.User
+	registered := true
+	if err := models.DB.Where("LOWER(email) = ?", email).First(&invitee).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			utils.Logger.Errorf("Failed to retrieve invitee: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+			return
+		}
+		registered = false
+	}
+	if registered {
+		isMember, err := models.UserHasAccessToTeam(invitee.ID, team.ID)
+		if err != nil {
+			utils.Logger.Errorf("Failed to check team membership: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+			return
+		}
+		if isMember {
+			utils.ErrorResponse(c, http.StatusConflict, "User is already a member of the team")
+			return
+		}
+	}
+
+	now := time.Now()
+	var pending int64
+	if err := models.OpenTeamInvitations(models.DB.Model(&models.TeamInvitation{}), now).
+		Where("team_id = ? AND email = ?", team.ID, email).
+		Count(&pending).Error; err != nil {
+		utils.Logger.Errorf("Failed to check pending invitations: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+		return
+	}
+	if pending > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "An invitation for this email is already pending")
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate invitation token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+		return
+	}
+
+	invitation := models.TeamInvitation{
+		TeamID:      team.ID,
+		Email:       email,
+		Role:        req.Role,
+		TokenHash:   utils.HashToken(token),
+		Status:      models.TeamInvitationPending,
+		InvitedByID: &user.ID,
+		ExpiresAt:   now.Add(config.AppConfig.Security.TeamInvitationTTL),
+	}
+	if err := models.DB.Create(&invitation).Error; err != nil {
+		utils.Logger.Errorf("Failed to create team invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+		return
+	}
+
+	// Registered users who turned off email notifications still find the invitation in their list
+	// The invitee's preferences are loaded directly, userPreferences caches those of the caller
+	prefs := models.DefaultUserPreferences(0)
+	if registered {
+		var err error
+		if prefs, err = models.GetUserPreferences(invitee.ID); err != nil {
+			utils.Logger.Errorf("Failed to retrieve preferences of UserID %d: %v", invitee.ID, err)
+			prefs = models.DefaultUserPreferences(invitee.ID)
+		}
+	}
+	if !registered || prefs.EmailNotifications {
+		emailService := utils.NewEmailService()
+		if err := emailService.SendTeamInvitationEmail(email, token, team.Name, user.Username, invitation.ExpiresAt, prefs); err != nil {
+			utils.Logger.Errorf("Failed to send team invitation email: %v", err)
+		}
+	}
+
+	utils.Logger.Infof("Team invitation created: InvitationID %d for TeamID %d by UserID %d", invitation.ID, team.ID, user.ID)
+
+	utils.CreatedResponse(c, invitation)
+}
+
+// ListTeamInvitations handles GET /teams/:team_id/invitations/
+// Only invitations that can still be answered are listed.
+func ListTeamInvitations(c *gin.Context) {
+	team, ok := findTeam(c)
+	if !ok {
+		return
+	}
+
+	invitations := []models.TeamInvitation{}
+	if err := models.OpenTeamInvitations(models.DB, time.Now()).
+		Where("team_id = ?", team.ID).
+		Order("created_at desc").
+		Find(&invitations).Error; err != nil {
+		utils.Logger.Errorf("Failed to retrieve team invitations: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve invitations")
+		return
+	}
+
+	utils.SuccessResponse(c, invitations)
+}
+
+// RevokeTeamInvitation handles DELETE /teams/:team_id/invitations/:invitation_id
+func RevokeTeamInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	team, ok := findTeam(c)
+	if !ok {
+		return
+	}
+
+	invitationID, err := strconv.ParseUint(c.Param("invitation_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	var invitation models.TeamInvitation
+	if err := models.DB.Where("id = ? AND team_id = ?", invitationID, team.ID).First(&invitation).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Invitation not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to retrieve team invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke invitation")
+		return
+	}
+
+	closed, err := models.CloseTeamInvitation(models.DB, invitation.ID, models.TeamInvitationRevoked, time.Now())
+	if err != nil {
+		utils.Logger.Errorf("Failed to revoke team invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke invitation")
+		return
+	}
+	if !closed {
+		utils.ErrorResponse(c, http.StatusConflict, "Invitation is no longer pending")
+		return
+	}
+
+	utils.Logger.Infof("Team invitation revoked: InvitationID %d by UserID %d", invitation.ID, user.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// ListMyTeamInvitations handles GET /users/invitations
+// It lists the open invitations sent to the address of the current user, which has to be verified.
+func ListMyTeamInvitations(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.IsEmailVerified {
+		utils.ErrorResponse(c, http.StatusForbidden, "Verify your email address to see the invitations sent to it")
+		return
+	}
+
+	var invitations []models.TeamInvitation
+	if err := models.OpenTeamInvitations(models.DB, time.Now()).
+		Preload("Team").Preload("InvitedBy").
+		Where("email = ?", strings.ToLower(user.Email)).
+		Order("created_at desc").
+		Find(&invitations).Error; err != nil {
+		utils.Logger.Errorf("Failed to retrieve team invitations: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve invitations")
+		return
+	}
+
+	responseData := []gin.H{}
+	for _, invitation := range invitations {
+		item := gin.H{
+			"id":         invitation.ID,
+			"team_id":    invitation.TeamID,
+			"role":       invitation.Role,
+			"expires_at": invitation.ExpiresAt,
+			"created_at": invitation.CreatedAt,
+		}
+		if invitation.Team != nil {
+			item["team_name"] = invitation.Team.Name
+		}
+		if invitation.InvitedBy != nil {
+			item["invited_by"] = invitation.InvitedBy.Username
+		}
+		responseData = append(responseData, item)
+	}
+
+	utils.SuccessResponse(c, responseData)
+}
+
+// AcceptTeamInvitation handles POST /users/invitations/:invitation_id/accept
+func AcceptTeamInvitation(c *gin.Context) {
+	answerOwnTeamInvitation(c, true)
+}
+
+// DeclineTeamInvitation handles POST /users/invitations/:invitation_id/decline
+func DeclineTeamInvitation(c *gin.Context) {
+	answerOwnTeamInvitation(c, false)
+}
+
+// AcceptTeamInvitationByToken handles POST /users/invitations/accept with the token from the invitation email.
+// The token alone is not enough, since emails get forwarded: the account must own the invited, verified address.
+func AcceptTeamInvitationByToken(c *gin.Context) {
+	answerTeamInvitationByToken(c, true)
+}
+
+// DeclineTeamInvitationByToken handles POST /users/invitations/decline with the token from the invitation email
+func DeclineTeamInvitationByToken(c *gin.Context) {
+	answerTeamInvitationByToken(c, false)
+}
+
+// answerOwnTeamInvitation accepts or declines an invitation from the list of the current user
+func answerOwnTeamInvitation(c *gin.Context, accept bool) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	invitationID, err := strconv.ParseUint(c.Param("invitation_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	// Invitations for other addresses are reported as missing, so their IDs cannot be probed
+	var invitation models.TeamInvitation
+	err = models.DB.Where("id = ? AND email = ?", invitationID, strings.ToLower(user.Email)).First(&invitation).Error
+	if err == nil && !user.IsEmailVerified {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Invitation not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to retrieve team invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to answer invitation")
+		return
+	}
+
+	answerTeamInvitation(c, user, invitation, accept)
+}
+
+// answerTeamInvitationByToken accepts or declines the invitation identified by an emailed token
+func answerTeamInvitationByToken(c *gin.Context, accept bool) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TeamInvitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var invitation models.TeamInvitation
+	if err := models.DB.Where("token_hash = ?", utils.HashToken(req.Token)).First(&invitation).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Invitation not found")
+			return
+		}
+		utils.Logger.Errorf("Failed to retrieve team invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to answer invitation")
+		return
+	}
+
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		utils.Logger.Warnf("Team invitation %d answered by UserID %d with a different email", invitation.ID, user.ID)
+		utils.ErrorResponse(c, http.StatusForbidden, "This invitation was sent to a different email address")
+		return
+	}
+	if !user.IsEmailVerified {
+		utils.ErrorResponse(c, http.StatusForbidden, "Verify your email address before answering the invitations sent to it")
+		return
+	}
+
+	answerTeamInvitation(c, user, invitation, accept)
+}
+
+// answerTeamInvitation closes the invitation and, when accepted, adds the user to the team with the invited role.
+// Users who are already members keep their current role.
+func answerTeamInvitation(c *gin.Context, user models.User, invitation models.TeamInvitation, accept bool) {
+	now := time.Now()
+	if invitation.Status != models.TeamInvitationPending {
+		utils.ErrorResponse(c, http.StatusConflict, "Invitation is no longer pending")
+		return
+	}
+	if !invitation.IsOpen(now) {
+		utils.ErrorResponse(c, http.StatusGone, "Invitation has expired")
+		return
+	}
+
+	status := models.TeamInvitationDeclined
+	if accept {
+		status = models.TeamInvitationAccepted
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		closed, err := models.CloseTeamInvitation(tx, invitation.ID, status, now)
+		if err != nil {
+			return err
+		}
+		if !closed {
+			return errInvitationUnusable
+		}
+		if !accept {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.TeamMembership{TeamID: invitation.TeamID, UserID: user.ID, Role: invitation.Role}).Error
+	})
+	if err != nil {
+		if err == errInvitationUnusable {
+			utils.ErrorResponse(c, http.StatusConflict, "Invitation is no longer pending")
+			return
+		}
+		utils.Logger.Errorf("Failed to answer team invitation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to answer invitation")
+		return
+	}
+
+	utils.Logger.Infof("Team invitation %s: InvitationID %d by UserID %d", status, invitation.ID, user.ID)
+
+	if !accept {
+		utils.SuccessResponse(c, gin.H{"message": "Invitation declined"})
+		return
+	}
+	utils.SuccessResponse(c, gin.H{
+		"message": "Invitation accepted",
+		"team_id": invitation.TeamID,
+	})
+}
+
+// findTeam retrieves the team named by the team_id parameter, writing the error response itself
+func findTeam(c *gin.Context) (models.Team, bool) {
+	teamID, err := strconv.ParseUint(c.Param("team_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid team ID")
+		return models.Team{}, false
+	}
+
+	var team models.Team
+	if err := models.DB.First(&team, teamID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Team not found")
+			return models.Team{}, false
+		}
+		utils.Logger.Errorf("Failed to retrieve team: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve team")
+		return models.Team{}, false
+	}
+	return team, true
+}
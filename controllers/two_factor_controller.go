@@ -0,0 +1,433 @@
+// controllers/two_factor_controller.go
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is the number of recovery codes issued when 2FA is enabled
+const recoveryCodeCount = 10
+
+// ConfirmTwoFactorRequest represents the request structure for confirming 2FA enrollment
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the request structure for disabling 2FA.
+// Either a TOTP code or a recovery code must be supplied together with the password.
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// RegenerateRecoveryCodesRequest represents the request structure for regenerating recovery codes
+type RegenerateRecoveryCodesRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyMFARequest represents the request structure for completing a login with a second factor
+type VerifyMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the user. 2FA stays disabled until confirmed.
+func EnrollTwoFactor(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		utils.ErrorResponse(c, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		utils.Logger.Errorf("Failed to generate TOTP secret: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to enroll two-factor authentication")
+		return
+	}
+
+	if err := models.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("totp_secret", secret).Error; err != nil {
+		utils.Logger.Errorf("Failed to save TOTP secret: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to enroll two-factor authentication")
+		return
+	}
+
+	utils.Logger.Infof("Two-factor enrollment started: UserID %d", user.ID)
+
+	utils.SuccessResponse(c, gin.H{
+		"secret":       secret,
+		"otpauth_uri":  utils.TOTPProvisioningURI(config.AppConfig.Security.TOTPIssuer, user.Email, secret),
+		"instructions": "Scan the QR code in your authenticator app, then confirm with a generated code.",
+	})
+}
+
+// ConfirmTwoFactor enables 2FA after the user proves the authenticator is set up, and returns recovery codes
+func ConfirmTwoFactor(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var req ConfirmTwoFactorRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if user.TOTPEnabled {
+		utils.ErrorResponse(c, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if user.TOTPSecret == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Two-factor enrollment has not been started")
+		return
+	}
+
+	step, valid := utils.ValidateTOTPCode(user.TOTPSecret, req.Code, time.Now())
+	if !valid {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid authentication code")
+		return
+	}
+
+	var codes []string
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"totp_enabled":        true,
+			"totp_last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		utils.Logger.Errorf("Failed to enable two-factor authentication: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+
+	utils.Logger.Infof("Two-factor authentication enabled: UserID %d", user.ID)
+
+	utils.SuccessResponse(c, gin.H{
+		"message":        "Two-factor authentication enabled. Store the recovery codes in a safe place; each can be used once.",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns 2FA off after re-checking the password and a second factor
+func DisableTwoFactor(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !user.TOTPEnabled {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+
+	if !user.ComparePassword(req.Password) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+
+	valid, err := verifySecondFactor(&user, req.Code, req.RecoveryCode)
+	if err != nil {
+		utils.Logger.Errorf("Failed to verify second factor: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+	if !valid {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authentication code")
+		return
+	}
+
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"totp_enabled":        false,
+			"totp_secret":         "",
+			"totp_last_used_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		utils.Logger.Errorf("Failed to disable two-factor authentication: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	utils.Logger.Infof("Two-factor authentication disabled: UserID %d", user.ID)
+
+	utils.SuccessResponse(c, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user with a fresh set
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var req RegenerateRecoveryCodesRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !user.TOTPEnabled {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+
+	valid, err := verifySecondFactor(&user, req.Code, "")
+	if err != nil {
+		utils.Logger.Errorf("Failed to verify second factor: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to regenerate recovery codes")
+		return
+	}
+	if !valid {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authentication code")
+		return
+	}
+
+	var codes []string
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		utils.Logger.Errorf("Failed to regenerate recovery codes: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to regenerate recovery codes")
+		return
+	}
+
+	utils.Logger.Infof("Recovery codes regenerated: UserID %d", user.ID)
+
+	utils.SuccessResponse(c, gin.H{"recovery_codes": codes})
+}
+
+// VerifyMFA exchanges an MFA challenge token and a second factor for the real tokens
+func VerifyMFA(c *gin.Context) {
+	var req VerifyMFARequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Either code or recovery_code is required")
+		return
+	}
+
+	// The challenge is only used up once the second factor is correct, so a mistyped code can be retried
+	challengeHash := utils.HashToken(req.MFAToken)
+	challenge, err := models.FindValidToken(models.DB, challengeHash, models.TokenTypeMFAChallenge)
+	if err != nil {
+		if err == models.ErrTokenInvalid {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired MFA token")
+			return
+		}
+		utils.Logger.Errorf("Failed to find MFA challenge: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify MFA")
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, challenge.UserID).Error; err != nil {
+		utils.Logger.Errorf("User not found for MFA challenge: %v", err)
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired MFA token")
+		return
+	}
+
+	// Failed codes count towards the same lockout as failed passwords
+	if !checkAccountLoginThrottle(c, user) {
+		return
+	}
+
+	if !checkAccountStatus(c, user) {
+		return
+	}
+
+	valid, err := verifySecondFactor(&user, req.Code, req.RecoveryCode)
+	if err != nil {
+		utils.Logger.Errorf("Failed to verify second factor: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify MFA")
+		return
+	}
+	if !valid {
+		if registerFailedLogin(c, &user) {
+			utils.ErrorResponse(c, http.StatusLocked, "Account is temporarily locked due to too many failed login attempts")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authentication code")
+		return
+	}
+
+	// The challenge is single-use
+	if _, err := models.ConsumeToken(models.DB, challengeHash, models.TokenTypeMFAChallenge); err != nil {
+		if err == models.ErrTokenInvalid {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired MFA token")
+			return
+		}
+		utils.Logger.Errorf("Failed to consume MFA challenge: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify MFA")
+		return
+	}
+
+	registerSuccessfulLogin(c, &user)
+
+	tokens, _, err := issueTokenPair(c, models.DB, user, "")
+	if err != nil {
+		utils.Logger.Errorf("Failed to issue tokens: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login")
+		return
+	}
+
+	utils.Logger.Infof("User logged in successfully with second factor: %s", user.Email)
+
+	sendTokens(c, tokens, true)
+}
+
+// finishLogin completes a login for an authenticated user: users with 2FA enabled receive an
+// MFA challenge token, everyone else receives the access and refresh tokens directly.
+// Callers must have checked everything else that can refuse the login, such as email verification,
+// because the login is registered as successful here.
+func finishLogin(c *gin.Context, user models.User) {
+	if !checkAccountStatus(c, user) {
+		return
+	}
+
+	// With 2FA enabled the failure counter is only cleared once the second factor is verified,
+	// so a known password cannot be used to reset the lockout between code guesses
+	if !user.TOTPEnabled {
+		registerSuccessfulLogin(c, &user)
+	}
+
+	if user.TOTPEnabled {
+		ttl := config.AppConfig.Security.MFAChallengeTTL
+		challengeToken, err := issueToken(models.DB, user.ID, models.TokenTypeMFAChallenge, time.Now().Add(ttl))
+		if err != nil {
+			utils.Logger.Errorf("Failed to create MFA challenge: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login")
+			return
+		}
+
+		utils.Logger.Infof("MFA challenge issued for user: %s", user.Email)
+
+		utils.SuccessResponse(c, gin.H{
+			"mfa_required": true,
+			"mfa_token":    challengeToken,
+			"expires_in":   int(ttl.Seconds()),
+		})
+		return
+	}
+
+	// Generate access token and a new refresh token family
+	tokens, _, err := issueTokenPair(c, models.DB, user, "")
+	if err != nil {
+		utils.Logger.Errorf("Failed to issue tokens: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login")
+		return
+	}
+
+	utils.Logger.Infof("User logged in successfully: %s", user.Email)
+
+	// Send success response with tokens
+	sendTokens(c, tokens, true)
+}
+
+// verifySecondFactor checks a TOTP code or, if no code is given, a recovery code.
+// TOTP codes cannot be replayed and recovery codes are consumed on success.
+func verifySecondFactor(user *models.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, valid := utils.ValidateTOTPCode(user.TOTPSecret, code, time.Now())
+		if !valid || step <= user.TOTPLastUsedStep {
+			return false, nil
+		}
+
+		// Conditional update so the same code cannot be accepted twice concurrently
+		result := models.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_used_step < ?", user.ID, step).
+			Update("totp_last_used_step", step)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return false, nil
+		}
+		user.TOTPLastUsedStep = step
+		return true, nil
+	}
+
+	if recoveryCode != "" {
+		result := models.DB.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode))).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return false, nil
+		}
+		utils.Logger.Infof("Recovery code used: UserID %d", user.ID)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// replaceRecoveryCodes deletes the existing recovery codes of the user and stores a new set.
+// The plain codes are returned so they can be shown to the user once.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(code),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
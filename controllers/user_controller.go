@@ -2,43 +2,41 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lib/pq"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
 	"github.com/mfuadfakhruzzaki/backendaurauran/models"
 	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // GetProfile handles retrieving the user's profile
 func GetProfile(c *gin.Context) {
-	// **Perbaikan: Mengambil objek User dari konteks dengan kunci "user"**
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
+	user, ok := loadCurrentUser(c)
 	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
 	// Prepare response data without sending password
 	responseData := gin.H{
-		"id":                user.ID,
-		"username":          user.Username,
-		"email":             user.Email,
-		"role":              user.Role,
-		"is_email_verified": user.IsEmailVerified,
-		"created_at":        user.CreatedAt,
-		"updated_at":        user.UpdatedAt,
+		"id":                    user.ID,
+		"username":              user.Username,
+		"email":                 user.Email,
+		"role":                  user.Role,
+		"is_email_verified":     user.IsEmailVerified,
+		"pending_email":         user.PendingEmail,
+		"avatar_url":            user.AvatarURL,
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+		"created_at":            user.CreatedAt,
+		"updated_at":            user.UpdatedAt,
 	}
 
 	utils.SuccessResponse(c, responseData)
@@ -48,23 +46,13 @@ func GetProfile(c *gin.Context) {
 type UpdateProfileRequest struct {
 	Username string `json:"username" binding:"omitempty"`
 	Email    string `json:"email" binding:"omitempty,email"`
-	Password string `json:"password" binding:"omitempty,min=6"`
+	Password string `json:"password" binding:"omitempty"`
 }
 
 // UpdateProfile handles updating the user's profile
 func UpdateProfile(c *gin.Context) {
-	// **Perbaikan: Mengambil objek User dari konteks dengan kunci "user"**
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	user, ok := userInterface.(models.User)
+	user, ok := loadCurrentUser(c)
 	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
@@ -86,14 +74,27 @@ func UpdateProfile(c *gin.Context) {
 		user.Username = req.Username
 	}
 
-	// Update email if provided and different
-	if req.Email != "" && req.Email != user.Email {
-		user.Email = req.Email
-		user.IsEmailVerified = false // Mark email as unverified
+	// A new email is only stored as pending until it is confirmed from the new address
+	changeEmail := req.Email != "" && req.Email != user.Email
+	if changeEmail {
+		var count int64
+		if err := models.DB.Model(&models.User{}).Where("email = ? AND id <> ?", req.Email, user.ID).Count(&count).Error; err != nil {
+			utils.Logger.Errorf("Failed to check existing email: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update profile")
+			return
+		}
+		if count > 0 {
+			utils.ErrorResponse(c, http.StatusConflict, "Email already registered")
+			return
+		}
 	}
 
 	// Update password if provided
 	if req.Password != "" {
+		if !checkPasswordPolicy(c, req.Password, user.Username, user.Email, user.ID) {
+			return
+		}
+
 		// **Perbaikan: Meng-hash password sebelum disimpan**
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
@@ -105,7 +106,15 @@ func UpdateProfile(c *gin.Context) {
 	}
 
 	// Save changes to the database
-	if err := models.DB.Save(&user).Error; err != nil {
+	if err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		if req.Password != "" {
+			return recordPasswordHistory(tx, user.ID, user.Password)
+		}
+		return nil
+	}); err != nil {
 		if isUniqueConstraintError(err) {
 			utils.ErrorResponse(c, http.StatusConflict, "Email or username already exists")
 			return
@@ -115,39 +124,25 @@ func UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	// If email was changed, send verification email
-	if req.Email != "" && req.Email != user.Email {
-		// Generate new email verification token
-		verifyToken, err := utils.GenerateRandomToken(32)
-		if err != nil {
-			utils.Logger.Errorf("Failed to generate verification token: %v", err)
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update profile")
-			return
-		}
-
-		emailToken := models.Token{
-			UserID:    user.ID,
-			Token:     verifyToken,
-			Type:      models.TokenTypeEmailVerify,
-			ExpiresAt: time.Now().Add(24 * time.Hour), // Token valid for 24 hours
-		}
-
-		if err := models.DB.Create(&emailToken).Error; err != nil {
-			utils.Logger.Errorf("Failed to save verification token: %v", err)
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update profile")
+	// A new password signs out every other session, the one making the change stays logged in
+	if req.Password != "" {
+		if err := models.RevokeOtherUserSessions(user.ID, c.GetString(utils.ContextSessionKey)); err != nil {
+			utils.Logger.Errorf("Failed to revoke sessions after password change: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke sessions")
 			return
 		}
+	}
 
-		// **Perbaikan: Mengirim email verifikasi dengan URL yang diformat dengan benar**
-		emailService := utils.NewEmailService()
-		verifyURL := fmt.Sprintf("https://yourdomain.com/auth/verify-email?token=%s", verifyToken)
-		if err := emailService.SendVerificationEmail(user.Email, verifyURL); err != nil {
-			utils.Logger.Errorf("Failed to send verification email: %v", err)
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send verification email")
+	// If email was changed, send the confirmation link to the new address
+	if changeEmail {
+		if err := requestEmailChange(user, req.Email); err != nil {
+			utils.Logger.Errorf("Failed to request email change: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send confirmation email")
 			return
 		}
+		user.PendingEmail = req.Email
 
-		utils.Logger.Infof("Verification email sent to: %s", user.Email)
+		utils.Logger.Infof("Email change confirmation sent to: %s", req.Email)
 	}
 
 	utils.Logger.Infof("User profile updated successfully: UserID %d", user.ID)
@@ -159,6 +154,8 @@ func UpdateProfile(c *gin.Context) {
 		"email":             user.Email,
 		"role":              user.Role,
 		"is_email_verified": user.IsEmailVerified,
+		"pending_email":     user.PendingEmail,
+		"avatar_url":        user.AvatarURL,
 		"created_at":        user.CreatedAt,
 		"updated_at":        user.UpdatedAt,
 	}
@@ -166,43 +163,221 @@ func UpdateProfile(c *gin.Context) {
 	utils.SuccessResponse(c, responseData)
 }
 
-// DeleteProfile handles deleting the user's account (Optional)
+// DeleteProfileRequest represents the request structure for deleting the account
+type DeleteProfileRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeleteProfile handles scheduling the account of the current user for deletion.
+// Personal data is removed after the grace period unless the user logs in again before then.
+// Owned projects and teams have to be transferred first so teammates keep their shared work.
 func DeleteProfile(c *gin.Context) {
-	// **Perbaikan: Mengambil objek User dari konteks dengan kunci "user"**
-	userInterface, exists := c.Get("user")
-	if !exists {
-		utils.Logger.Warn("User not found in context")
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+	user, ok := loadCurrentUser(c)
+	if !ok {
 		return
 	}
 
-	user, ok := userInterface.(models.User)
-	if !ok {
-		utils.Logger.Warn("User type assertion failed")
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+	var req DeleteProfileRequest
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Delete user from the database (soft delete if using gorm.DeletedAt)
-	if err := models.DB.Delete(&user).Error; err != nil {
-		utils.Logger.Errorf("Failed to delete user: %v", err)
+	if !user.ComparePassword(req.Password) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+
+	if user.DeletionScheduledAt != nil {
+		utils.ErrorResponse(c, http.StatusConflict, "Account deletion is already scheduled")
+		return
+	}
+
+	projects, teams, err := models.OwnedResources(user.ID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to retrieve owned resources: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete account")
 		return
 	}
+	if len(projects) > 0 || len(teams) > 0 {
+		ownedProjects := []gin.H{}
+		for _, project := range projects {
+			ownedProjects = append(ownedProjects, gin.H{"id": project.ID, "title": project.Title})
+		}
+		ownedTeams := []gin.H{}
+		for _, team := range teams {
+			ownedTeams = append(ownedTeams, gin.H{"id": team.ID, "name": team.Name})
+		}
+		utils.ErrorResponseWithDetails(c, http.StatusConflict, "Transfer ownership of your projects and teams before deleting your account",
+			gin.H{"projects": ownedProjects, "teams": ownedTeams})
+		return
+	}
 
-	utils.Logger.Infof("User account deleted successfully: UserID %d", user.ID)
+	deleteAt := time.Now().Add(config.AppConfig.Security.AccountDeletionGracePeriod)
+	if err := models.ScheduleAccountDeletion(user.ID, deleteAt); err != nil {
+		utils.Logger.Errorf("Failed to schedule account deletion: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete account")
+		return
+	}
+
+	// Logging in again cancels the deletion, so every existing session and token ends here
+	if err := revokeAllAccess(user.ID); err != nil {
+		utils.Logger.Errorf("Failed to revoke access of deleted account: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete account")
+		return
+	}
+
+	emailService := utils.NewEmailService()
+	if err := emailService.SendAccountDeletionScheduledEmail(user.Email, deleteAt, userPreferences(c, user.ID)); err != nil {
+		utils.Logger.Errorf("Failed to send account deletion email: %v", err)
+	}
+
+	utils.Logger.Infof("Account deletion scheduled: UserID %d at %s", user.ID, deleteAt.Format(time.RFC3339))
 
 	// Send success response
-	utils.SuccessResponse(c, gin.H{"message": "Account deleted successfully"})
+	utils.SuccessResponse(c, gin.H{
+		"message":               "Account scheduled for deletion. Log in again before the deletion date to cancel.",
+		"deletion_scheduled_at": deleteAt,
+	})
+}
+
+// ExportProfile handles downloading a JSON archive with the profile of the current user and
+// everything they created or take part in
+func ExportProfile(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	sections := []struct {
+		name  string
+		query *gorm.DB
+	}{
+		{"owned_projects", models.DB.Model(&models.Project{}).Where("owner_id = ?", user.ID)},
+		{"collaborations", models.DB.Model(&models.Collaboration{}).Where("user_id = ?", user.ID)},
+		{"teams", models.DB.Model(&models.Team{}).
+			Where("deleted_at IS NULL AND (owner_id = ? OR id IN (SELECT team_id FROM team_members WHERE user_id = ?))", user.ID, user.ID)},
+		{"assigned_tasks", models.DB.Model(&models.Task{}).Where("assigned_to_id = ?", user.ID)},
+		{"notes", models.DB.Model(&models.Note{}).Where("user_id = ?", user.ID)},
+		{"activities", models.DB.Model(&models.Activity{}).Where("user_id = ?", user.ID)},
+		{"files", models.DB.Model(&models.File{}).Where("uploaded_by = ?", user.ID)},
+		{"notifications", models.DB.Model(&models.Notification{}).Where("user_id = ?", user.ID)},
+		{"sessions", models.DB.Model(&models.Session{}).Where("user_id = ?", user.ID)},
+		{"identities", models.DB.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID)},
+		{"personal_access_tokens", models.DB.Model(&models.PersonalAccessToken{}).Omit("token_hash").Where("user_id = ?", user.ID)},
+	}
+
+	prefs, err := models.GetUserPreferences(user.ID)
+	if err != nil {
+		utils.Logger.Errorf("Failed to export preferences: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export account data")
+		return
+	}
+
+	archive := gin.H{
+		"exported_at": time.Now(),
+		"profile":     newUserAccount(user),
+		"preferences": prefs,
+	}
+	for _, section := range sections {
+		rows := []map[string]interface{}{}
+		if err := section.query.Order("id").Find(&rows).Error; err != nil {
+			utils.Logger.Errorf("Failed to export %s: %v", section.name, err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export account data")
+			return
+		}
+		archive[section.name] = rows
+	}
+
+	utils.Logger.Infof("Account data exported: UserID %d", user.ID)
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d.json"`, user.ID))
+	c.IndentedJSON(http.StatusOK, archive)
+}
+
+// minSearchQueryLength is the shortest search query accepted, so a single letter cannot list most accounts
+const minSearchQueryLength = 2
+
+// UserSearchResult is the public part of a user returned by SearchUsers
+type UserSearchResult struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// SearchUsers handles GET /users/search?q=
+// It matches the start of the username or email. Results are limited to users who share a team or
+// project with the caller, except for admins, and paginated with page and page_size.
+func SearchUsers(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	if len([]rune(q)) < minSearchQueryLength {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("q must be at least %d characters", minSearchQueryLength))
+		return
+	}
+
+	pagination, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	pattern := likePattern(q, true)
+	query := models.DB.Model(&models.User{}).
+		Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern).
+		Where("status = ?", models.UserStatusActive)
+	if user.Role != models.RoleAdmin {
+		query = query.Where("id IN (?)", models.UsersSharingWorkWith(user.ID))
+	}
+
+	page, err := paginate(query, &pagination)
+	if err != nil {
+		utils.Logger.Errorf("Failed to count users: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to search users")
+		return
+	}
+
+	var users []models.User
+	if err := page.Select("id", "username", "email", "avatar_key").Order("username").Find(&users).Error; err != nil {
+		utils.Logger.Errorf("Failed to search users: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to search users")
+		return
+	}
+
+	results := make([]UserSearchResult, len(users))
+	for i, found := range users {
+		results[i] = UserSearchResult{
+			ID:        found.ID,
+			Username:  found.Username,
+			Email:     found.Email,
+			AvatarURL: found.AvatarURL,
+		}
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"users":      results,
+		"pagination": pagination,
+	})
 }
 
-// isUniqueConstraintError checks if an error is due to a unique constraint violation in PostgreSQL
+// uniqueViolationCode is the PostgreSQL error code of a unique constraint violation
+const uniqueViolationCode = "23505"
+
+// isUniqueConstraintError checks if an error is due to a unique constraint violation in PostgreSQL.
+// The gorm postgres driver reports errors from pgx; lib/pq errors are still recognized.
 func isUniqueConstraintError(err error) bool {
-	if err == nil {
-		return false
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == uniqueViolationCode
 	}
-	if pqErr, ok := err.(*pq.Error); ok {
-		return pqErr.Code == "23505"
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == uniqueViolationCode
 	}
 	return false
 }
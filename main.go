@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/mfuadfakhruzzaki/backendaurauran/cache"
 	"github.com/mfuadfakhruzzaki/backendaurauran/config"
 	"github.com/mfuadfakhruzzaki/backendaurauran/models"
 	"github.com/mfuadfakhruzzaki/backendaurauran/routes"
@@ -32,6 +34,17 @@ func main() {
 	// Initialize models
 	models.InitModels(db)
 
+	// AuthMiddleware caches users briefly; writes to the users table invalidate the cache
+	models.UserCacheTTL = config.AppConfig.Security.UserCacheTTL
+	if err := models.RegisterUserCacheCallbacks(db); err != nil {
+		utils.Logger.Fatalf("Failed to register user cache callbacks: %v", err)
+	}
+
+	// Team members are stored with their role, the join table model has to be known before migrating
+	if err := models.SetupTeamMembership(db); err != nil {
+		utils.Logger.Fatalf("Failed to set up team membership: %v", err)
+	}
+
 	// Run migrations
 	if err := db.AutoMigrate(
 		&models.User{},
@@ -44,10 +57,63 @@ func main() {
 		&models.Notification{},
 		&models.EmailVerificationToken{},
 		&models.Token{},
+		&models.SigningKey{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.Invitation{},
+		&models.LoginAttempt{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.OAuthState{},
+		&models.PersonalAccessToken{},
+		&models.PasswordHistory{},
+		&models.UserPreferences{},
+		&models.TeamInvitation{},
 	); err != nil {
 		utils.Logger.Fatalf("Failed to run auto migrations: %v", err)
 	}
 
+	// Give team owners a membership with the owner role
+	if err := models.EnsureTeamOwnerMemberships(db); err != nil {
+		utils.Logger.Fatalf("Failed to migrate team owners: %v", err)
+	}
+
+	// Hash the tokens of databases created before tokens were stored as digests
+	if err := models.MigrateTokenHashes(db); err != nil {
+		utils.Logger.Fatalf("Failed to migrate tokens: %v", err)
+	}
+
+	// Allow the first admin to register when no admin exists yet
+	if err := models.EnsureBootstrapInvitation(config.AppConfig.Security.BootstrapAdminCode); err != nil {
+		utils.Logger.Fatalf("Failed to create bootstrap admin invitation: %v", err)
+	}
+
+	// Load the breached password list used by the password policy
+	if err := utils.LoadBreachedPasswords(); err != nil {
+		utils.Logger.Fatalf("Failed to load breached password list: %v", err)
+	}
+
+	// Load the access token signing keys, creating the first one on a fresh database
+	if err := utils.InitTokenService(); err != nil {
+		utils.Logger.Fatalf("Failed to initialize token service: %v", err)
+	}
+
+	// Set up the revocation store and load sessions that were revoked before startup
+	revocations, err := cache.NewRevocationStore(config.AppConfig.Security.RevocationStore)
+	if err != nil {
+		utils.Logger.Fatalf("Failed to initialize revocation store: %v", err)
+	}
+	models.Revocations = revocations
+
+	lastSync, err := models.SyncRevokedSessions(time.Time{})
+	if err != nil {
+		utils.Logger.Fatalf("Failed to load revoked sessions: %v", err)
+	}
+
+	// Start background jobs
+	go runRevocationSync(lastSync)
+	go runKeyRotation()
+
 	// Load storage configuration
 	storageConfig := config.LoadStorageConfig()
 
@@ -57,6 +123,9 @@ func main() {
 		utils.Logger.Fatalf("Failed to initialize storage service: %v", err)
 	}
 
+	// The cleanup job removes the avatars of purged accounts from storage
+	go runCleanup(storageService, storageConfig.BucketName)
+
 	// Setup router with all routes
 	router := routes.SetupRouter(db, storageService, storageConfig.BucketName)
 
@@ -101,3 +170,63 @@ func initializeS3StorageService(storageConfig config.StorageConfig) (storage.Sto
 	utils.Logger.Println("S3 Storage Service initialized successfully")
 	return s3Service, nil
 }
+
+// runRevocationSync periodically copies newly revoked sessions into the revocation store,
+// so revocations made through other instances are picked up
+func runRevocationSync(since time.Time) {
+	ticker := time.NewTicker(config.AppConfig.Security.RevocationSyncInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		next, err := models.SyncRevokedSessions(since)
+		if err != nil {
+			utils.Logger.Errorf("Failed to sync revoked sessions: %v", err)
+			continue
+		}
+		since = next
+	}
+}
+
+// runCleanup periodically deletes expired tokens, sessions and old login attempts,
+// and anonymizes accounts whose deletion grace period has ended
+func runCleanup(storageService storage.StorageService, bucketName string) {
+	ticker := time.NewTicker(config.AppConfig.Security.CleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := models.PurgeExpiredRecords(time.Now(), config.AppConfig.Security.LoginAttemptRetention)
+		if err != nil {
+			utils.Logger.Errorf("Failed to purge expired records: %v", err)
+			continue
+		}
+		utils.Logger.Infof("Purged expired records: %v", deleted)
+
+		purged, skipped, err := models.PurgeDeletedAccounts(time.Now(), func(avatarKey string) {
+			if err := utils.DeleteAvatarObjects(context.Background(), storageService, bucketName, avatarKey); err != nil {
+				utils.Logger.Errorf("Failed to delete avatar %s of purged account: %v", avatarKey, err)
+			}
+		})
+		if err != nil {
+			utils.Logger.Errorf("Failed to purge deleted accounts: %v", err)
+		}
+		if purged > 0 {
+			utils.Logger.Infof("Anonymized %d deleted accounts", purged)
+		}
+		if len(skipped) > 0 {
+			utils.Logger.Warnf("Skipped deleted accounts that still own projects or teams: %v", skipped)
+		}
+	}
+}
+
+// runKeyRotation periodically checks whether the signing key is due for rotation.
+// Instances pick up a rotation done elsewhere on their own, since the token service reloads stale keys.
+func runKeyRotation() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := utils.Tokens.RotateIfDue(); err != nil {
+			utils.Logger.Errorf("Failed to rotate signing key: %v", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// middlewares/access_token.go
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
+)
+
+// accessTokenTouchInterval limits how often LastUsedAt is written for a personal access token
+const accessTokenTouchInterval = time.Minute
+
+// authenticatePersonalAccessToken looks up a personal access token by its hash and checks that it is active.
+// It writes the error response itself and returns false when the token is rejected.
+func authenticatePersonalAccessToken(c *gin.Context, tokenStr string) (*models.PersonalAccessToken, bool) {
+	var pat models.PersonalAccessToken
+	if err := models.DB.Where("token_hash = ?", utils.HashToken(tokenStr)).First(&pat).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.Logger.Warnf("Unknown personal access token used: %s", maskToken(tokenStr))
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
+			return nil, false
+		}
+		utils.Logger.Errorf("Error retrieving personal access token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+		return nil, false
+	}
+
+	now := time.Now()
+	if !pat.IsActive(now) {
+		utils.Logger.Warnf("Revoked or expired personal access token used: TokenID %d", pat.ID)
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
+		return nil, false
+	}
+
+	// Track usage without writing on every request
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) >= accessTokenTouchInterval {
+		if err := models.DB.Model(&models.PersonalAccessToken{}).Where("id = ?", pat.ID).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": c.ClientIP(),
+		}).Error; err != nil {
+			utils.Logger.Errorf("Failed to update personal access token usage: %v", err)
+		}
+	}
+
+	return &pat, true
+}
+
+// ScopeMiddleware enforces the scopes of personal access tokens for a resource.
+// Read-only methods need "<resource>:read", all other methods "<resource>:write".
+// A token restricted to a project can only reach routes of that project.
+// Requests authenticated with a login session are not restricted.
+func ScopeMiddleware(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pat, ok := personalAccessToken(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		scope := resource + ":write"
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = resource + ":read"
+		}
+
+		if !pat.HasScope(scope) {
+			utils.Logger.Warnf("Personal access token %d lacks scope %s", pat.ID, scope)
+			utils.ErrorResponse(c, http.StatusForbidden, "Token does not have the required scope: "+scope)
+			c.Abort()
+			return
+		}
+
+		if pat.ProjectID != nil {
+			projectID, err := strconv.ParseUint(c.Param("project_id"), 10, 64)
+			if err != nil || uint(projectID) != *pat.ProjectID {
+				utils.Logger.Warnf("Personal access token %d used outside its project", pat.ID)
+				utils.ErrorResponse(c, http.StatusForbidden, "Token is restricted to another project")
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// SessionOnlyMiddleware rejects personal access tokens, for account and admin routes
+// that must only be reachable after an interactive login
+func SessionOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if pat, ok := personalAccessToken(c); ok {
+			utils.Logger.Warnf("Personal access token %d used on a session-only route", pat.ID)
+			utils.ErrorResponse(c, http.StatusForbidden, "This endpoint cannot be used with a personal access token")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// personalAccessToken returns the personal access token that authenticated the request, if any
+func personalAccessToken(c *gin.Context) (*models.PersonalAccessToken, bool) {
+	value, exists := c.Get(ContextAccessTokenKey)
+	if !exists {
+		return nil, false
+	}
+	pat, ok := value.(*models.PersonalAccessToken)
+	return pat, ok
+}
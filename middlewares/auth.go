@@ -4,89 +4,93 @@ package middlewares
 import (
 	"errors"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mfuadfakhruzzaki/backendaurauran/models"
 	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
 	"gorm.io/gorm"
-
-	"github.com/golang-jwt/jwt/v4" // Use maintained JWT library
 )
 
 // Context keys as constants to avoid typos
 const (
-	ContextUserKey = "user"
+	ContextUserKey        = "user"
+	ContextUserRoleKey    = "user_role"
+	ContextSessionKey     = "session_id"
+	ContextAccessTokenKey = "personal_access_token"
+	ContextPermissionsKey = "permissions"
 )
 
-// CustomClaims represents the JWT claims structure
-type CustomClaims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
-	jwt.StandardClaims
-}
+// sessionTouchInterval limits how often LastSeenAt is written for a session
+const sessionTouchInterval = time.Minute
 
-// AuthMiddleware checks JWT token validity and blacklist status
+// AuthMiddleware checks JWT token validity and the status of its session.
+// Personal access tokens are accepted as well; their scopes are enforced by ScopeMiddleware.
+// With cookie sessions enabled the access token may come from the session cookie instead of the
+// Authorization header, in which case state-changing requests must carry the CSRF token.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			utils.Logger.Warn("Authorization header missing")
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Authorization header missing")
-			c.Abort()
-			return
-		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			utils.Logger.Warn("Invalid authorization header format")
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authorization header format")
+		tokenStr, ok := requestToken(c)
+		if !ok {
 			c.Abort()
 			return
 		}
-
-		tokenStr := parts[1]
 		// Avoid logging the entire token for security reasons
 		maskedToken := maskToken(tokenStr)
 		utils.Logger.Debugf("Received token: %s", maskedToken)
 
-		// Check if token is blacklisted
-		var blacklistedToken models.Token
-		err := models.DB.Where("token = ? AND type = ?", tokenStr, "jwt_blacklist").First(&blacklistedToken).Error
-		if err == nil {
-			// Token is blacklisted
-			utils.Logger.Warnf("Blacklisted token used: %s", maskedToken)
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Token has been revoked")
-			c.Abort()
-			return
-		} else if err != gorm.ErrRecordNotFound {
-			// Other database errors
-			utils.Logger.Errorf("Error checking token blacklist: %v", err)
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
-			c.Abort()
-			return
+		var userID uint
+		if strings.HasPrefix(tokenStr, models.PersonalAccessTokenPrefix) {
+			// Personal access token used by scripts and bots
+			pat, ok := authenticatePersonalAccessToken(c, tokenStr)
+			if !ok {
+				c.Abort()
+				return
+			}
+			userID = pat.UserID
+			c.Set(ContextAccessTokenKey, pat)
 		} else {
-			utils.Logger.Debug("Token not found in blacklist")
-		}
+			// Parse and validate token
+			claims, err := utils.ParseJWT(tokenStr)
+			if err == nil && claims.ID == "" {
+				err = errors.New("token has no session")
+			}
+			if err != nil {
+				utils.Logger.Warnf("Invalid token: %v", err)
+				utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
+				c.Abort()
+				return
+			}
 
-		// Parse and validate token
-		claims, err := parseJWT(tokenStr)
-		if err != nil {
-			utils.Logger.Warnf("Invalid token: %v", err)
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
-			c.Abort()
-			return
-		}
+			// Check the revocation store instead of the database
+			revoked, err := models.Revocations.IsRevoked(c.Request.Context(), claims.ID)
+			if err != nil {
+				utils.Logger.Errorf("Error checking session revocation: %v", err)
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+				c.Abort()
+				return
+			}
+			if revoked {
+				utils.Logger.Warnf("Token of revoked session used: %s", maskedToken)
+				utils.ErrorResponse(c, http.StatusUnauthorized, "Token has been revoked")
+				c.Abort()
+				return
+			}
 
-		utils.Logger.Debugf("Token valid for user_id: %d, role: %s", claims.UserID, claims.Role)
+			touchSession(claims.ID)
 
-		// Retrieve full User object from the database
-		var user models.User
-		if err := models.DB.First(&user, claims.UserID).Error; err != nil {
+			utils.Logger.Debugf("Token valid for user_id: %d, role: %s", claims.UserID, claims.Role)
+			userID = claims.UserID
+			c.Set(ContextSessionKey, claims.ID)
+		}
+
+		// Retrieve full User object, cached for a short time so most requests skip the database
+		user, err := models.GetCachedUser(userID)
+		if err != nil {
 			if err == gorm.ErrRecordNotFound {
-				utils.Logger.Warnf("User not found for ID: %d", claims.UserID)
+				utils.Logger.Warnf("User not found for ID: %d", userID)
 				utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
 			} else {
 				utils.Logger.Errorf("Error retrieving user: %v", err)
@@ -104,47 +108,90 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Accounts disabled by an admin lose access immediately, including their personal access tokens
+		if user.IsDisabled() {
+			utils.Logger.Warnf("Disabled user tried to access the API: UserID %d", user.ID)
+			utils.ErrorResponse(c, http.StatusForbidden, "Account is disabled")
+			c.Abort()
+			return
+		}
+		if user.PasswordResetRequired {
+			utils.ErrorResponse(c, http.StatusForbidden, "Password reset required")
+			c.Abort()
+			return
+		}
+
 		// Set the complete User object in context
 		c.Set(ContextUserKey, user)
+		c.Set(ContextUserRoleKey, user.Role)
 
 		c.Next()
 	}
 }
 
-// parseJWT parses and validates the JWT token.
-func parseJWT(tokenStr string) (*CustomClaims, error) {
-	// Define the claims structure
-	claims := &CustomClaims{}
-
-	// Parse the token with the claims
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		// Ensure the signing method is HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		// Return the secret key
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			return nil, errors.New("JWT_SECRET not set in environment")
+// requestToken returns the bearer token of the Authorization header, or the access token of the session cookie.
+// It writes the error response itself, so callers only need to abort when ok is false.
+func requestToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		tokenStr := utils.AccessTokenCookie(c)
+		if tokenStr == "" {
+			utils.Logger.Warn("Authorization header missing")
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Authorization header missing")
+			return "", false
 		}
-		return []byte(secret), nil
-	})
 
-	if err != nil {
-		return nil, err
+		// Browsers attach cookies to cross-site requests too, the CSRF header proves the request comes from our client
+		if !utils.IsSafeMethod(c.Request.Method) && !utils.ValidCSRFToken(c, c.GetHeader(utils.CSRFHeaderName)) {
+			utils.Logger.Warnf("Missing or invalid CSRF token for %s %s", c.Request.Method, c.Request.URL.Path)
+			utils.ErrorResponse(c, http.StatusForbidden, "Invalid CSRF token")
+			return "", false
+		}
+		return tokenStr, true
 	}
 
-	// Validate the token
-	if !token.Valid {
-		return nil, errors.New("invalid token")
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		utils.Logger.Warn("Invalid authorization header format")
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authorization header format")
+		return "", false
 	}
 
-	// Additional claims validation if needed
-	if claims.ExpiresAt < time.Now().Unix() {
-		return nil, errors.New("token has expired")
+	return parts[1], true
+}
+
+// sessionTouches remembers when each session's LastSeenAt was last written by this process
+var (
+	sessionTouchesMu sync.Mutex
+	sessionTouches   = make(map[string]time.Time)
+)
+
+// touchSession records that the session was used, at most once per sessionTouchInterval.
+// The write happens in the background so it does not delay the request.
+func touchSession(sessionID string) {
+	now := time.Now()
+
+	sessionTouchesMu.Lock()
+	if last, ok := sessionTouches[sessionID]; ok && now.Sub(last) < sessionTouchInterval {
+		sessionTouchesMu.Unlock()
+		return
+	}
+	sessionTouches[sessionID] = now
+	// Forget sessions that have not been seen recently so the map does not grow forever
+	if len(sessionTouches) > 10000 {
+		for id, last := range sessionTouches {
+			if now.Sub(last) >= sessionTouchInterval {
+				delete(sessionTouches, id)
+			}
+		}
 	}
+	sessionTouchesMu.Unlock()
 
-	return claims, nil
+	go func() {
+		if err := models.DB.Model(&models.Session{}).Where("id = ?", sessionID).Update("last_seen_at", now).Error; err != nil {
+			utils.Logger.Errorf("Failed to update session last seen: %v", err)
+		}
+	}()
 }
 
 // maskToken masks a JWT token for safe logging.
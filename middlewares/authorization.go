@@ -3,20 +3,22 @@ package middlewares
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mfuadfakhruzzaki/backendaurauran/models"
 	"github.com/mfuadfakhruzzaki/backendaurauran/utils"
+	"gorm.io/gorm"
 )
 
 // RoleMiddleware memeriksa apakah pengguna memiliki salah satu peran yang diizinkan
 func RoleMiddleware(allowedRoles ...models.Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Ambil peran pengguna dari konteks
-		roleInterface, exists := c.Get("user_role")
+		roleInterface, exists := c.Get(ContextUserRoleKey)
 		if !exists {
 			utils.Logger.Warn("User role not found in context")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
 			c.Abort()
 			return
 		}
@@ -24,7 +26,7 @@ func RoleMiddleware(allowedRoles ...models.Role) gin.HandlerFunc {
 		userRole, ok := roleInterface.(models.Role)
 		if !ok {
 			utils.Logger.Warn("User role has invalid type")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user role"})
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
 			c.Abort()
 			return
 		}
@@ -38,7 +40,90 @@ func RoleMiddleware(allowedRoles ...models.Role) gin.HandlerFunc {
 		}
 
 		utils.Logger.Warnf("User role '%s' not authorized", userRole)
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		utils.ErrorResponse(c, http.StatusForbidden, "You do not have permission to perform this action")
 		c.Abort()
 	}
 }
+
+// RequirePermission memeriksa izin pengguna berdasarkan matriks izin di models.
+// Izin dihitung untuk proyek pada parameter :project_id, untuk tim pada :team_id,
+// atau hanya dari peran global jika route tidak memiliki keduanya.
+// Izin yang sudah dihitung disimpan di konteks agar handler dapat memeriksa izin tambahan.
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userInterface, exists := c.Get(ContextUserKey)
+		if !exists {
+			utils.Logger.Warn("User not found in context")
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+			c.Abort()
+			return
+		}
+
+		user, ok := userInterface.(models.User)
+		if !ok {
+			utils.Logger.Warn("User type assertion failed")
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+			c.Abort()
+			return
+		}
+
+		permissions, ok := resolvePermissions(c, user)
+		if !ok {
+			c.Abort()
+			return
+		}
+		c.Set(ContextPermissionsKey, permissions)
+
+		if !permissions.Has(permission) {
+			utils.Logger.Warnf("UserID %d lacks permission %s for %s", user.ID, permission, c.Request.URL.Path)
+			utils.ForbiddenResponse(c, string(permission))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// resolvePermissions menghitung izin pengguna untuk resource pada route.
+// Respons error ditulis langsung, sehingga pemanggil cukup berhenti jika ok bernilai false.
+func resolvePermissions(c *gin.Context, user models.User) (models.PermissionSet, bool) {
+	var (
+		permissions models.PermissionSet
+		err         error
+		resource    string
+	)
+
+	switch {
+	case c.Param("project_id") != "":
+		resource = "Project"
+		projectID, parseErr := strconv.ParseUint(c.Param("project_id"), 10, 64)
+		if parseErr != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid project ID")
+			return permissions, false
+		}
+		permissions, err = models.ProjectPermissions(user, uint(projectID))
+	case c.Param("team_id") != "":
+		resource = "Team"
+		teamID, parseErr := strconv.ParseUint(c.Param("team_id"), 10, 64)
+		if parseErr != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid team ID")
+			return permissions, false
+		}
+		permissions, err = models.TeamPermissions(user, uint(teamID))
+	default:
+		return models.GlobalPermissions(user), true
+	}
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, resource+" not found")
+			return permissions, false
+		}
+		utils.Logger.Errorf("Failed to resolve permissions: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Internal server error")
+		return permissions, false
+	}
+
+	return permissions, true
+}
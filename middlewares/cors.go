@@ -8,12 +8,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// CORSMiddleware mengatur CORS untuk aplikasi
-func CORSMiddleware() gin.HandlerFunc {
+// CORSMiddleware mengatur CORS untuk aplikasi. allowedOrigins berisi satu origin per elemen,
+// karena request dengan cookie hanya diizinkan untuk origin yang cocok persis.
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
     configCORS := cors.Config{
-        AllowOrigins:     []string{"http://localhost:5173, https://zacht.tech"}, // Ganti dengan domain yang diizinkan di produksi
+        AllowOrigins:     allowedOrigins,
         AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-        AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Accept"},
+        AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Accept", "X-CSRF-Token", "X-Auth-Mode"},
         ExposeHeaders:    []string{"Content-Length"},
         AllowCredentials: true,
         MaxAge:           12 * time.Hour,
@@ -2,42 +2,122 @@
 package middlewares
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
 	"golang.org/x/time/rate"
 )
 
-var (
-    visitors = make(map[string]*rate.Limiter)
-    mu       sync.Mutex
-    // Limit per IP: misalnya 10 request per second dengan burst 20
-    rateLimit = rate.Every(time.Second / 10)
-    burst     = 20
-)
+// keyedLimiter menyimpan satu rate limiter per key (misalnya per IP).
+// Limiter yang lama tidak dipakai dihapus, sehingga berganti IP tidak membuat map terus bertambah.
+type keyedLimiter struct {
+    mu        sync.Mutex
+    limiters  map[string]*keyedLimiterEntry
+    limit     rate.Limit
+    burst     int
+    idleTTL   time.Duration // Setelah selama ini tidak dipakai, limiter sudah penuh lagi dan sama dengan limiter baru
+    lastSweep time.Time
+}
+
+// keyedLimiterEntry adalah rate limiter satu key beserta waktu terakhir dipakai
+type keyedLimiterEntry struct {
+    limiter  *rate.Limiter
+    lastSeen time.Time
+}
+
+// minLimiterSweepInterval membatasi seberapa sering map limiter disapu
+const minLimiterSweepInterval = time.Minute
+
+func newKeyedLimiter(limit rate.Limit, burst int) *keyedLimiter {
+    return &keyedLimiter{
+        limiters:  make(map[string]*keyedLimiterEntry),
+        limit:     limit,
+        burst:     burst,
+        idleTTL:   time.Duration(float64(burst) / float64(limit) * float64(time.Second)),
+        lastSweep: time.Now(),
+    }
+}
 
-// getVisitor mengembalikan rate limiter untuk IP tertentu
-func getVisitor(ip string) *rate.Limiter {
-    mu.Lock()
-    defer mu.Unlock()
+// get mengembalikan rate limiter untuk key tertentu
+func (k *keyedLimiter) get(key string) *rate.Limiter {
+    now := time.Now()
 
-    limiter, exists := visitors[ip]
+    k.mu.Lock()
+    defer k.mu.Unlock()
+
+    k.sweep(now)
+
+    entry, exists := k.limiters[key]
     if !exists {
-        limiter = rate.NewLimiter(rateLimit, burst)
-        visitors[ip] = limiter
+        entry = &keyedLimiterEntry{limiter: rate.NewLimiter(k.limit, k.burst)}
+        k.limiters[key] = entry
+    }
+    entry.lastSeen = now
+
+    return entry.limiter
+}
+
+// sweep menghapus limiter yang tidak dipakai lebih lama dari idleTTL. Dipanggil dengan mu terkunci.
+func (k *keyedLimiter) sweep(now time.Time) {
+    interval := k.idleTTL
+    if interval < minLimiterSweepInterval {
+        interval = minLimiterSweepInterval
+    }
+    if now.Sub(k.lastSweep) < interval {
+        return
     }
 
-    return limiter
+    for key, entry := range k.limiters {
+        if now.Sub(entry.lastSeen) >= k.idleTTL {
+            delete(k.limiters, key)
+        }
+    }
+    k.lastSweep = now
 }
 
+// Limit per IP: misalnya 10 request per second dengan burst 20
+var visitors = newKeyedLimiter(rate.Every(time.Second/10), 20)
+
 // RateLimitMiddleware membatasi jumlah request per IP
 func RateLimitMiddleware() gin.HandlerFunc {
+    return limitBy(visitors, clientIPKey)
+}
+
+// RouteRateLimitMiddleware membatasi request per IP untuk satu route dengan batas sendiri,
+// misalnya untuk endpoint yang mengirim email. Setiap pemanggilan memiliki penghitung terpisah.
+func RouteRateLimitMiddleware(requests int, per time.Duration) gin.HandlerFunc {
+    return limitBy(newKeyedLimiter(rate.Every(per/time.Duration(requests)), requests), clientIPKey)
+}
+
+// UserRateLimitMiddleware membatasi request per user untuk satu route, sehingga batas tidak bisa
+// dihindari dengan berganti IP. Harus dipasang setelah AuthMiddleware; tanpa user, batas dihitung per IP.
+func UserRateLimitMiddleware(requests int, per time.Duration) gin.HandlerFunc {
+    return limitBy(newKeyedLimiter(rate.Every(per/time.Duration(requests)), requests), userKey)
+}
+
+// clientIPKey menghitung batas per IP
+func clientIPKey(c *gin.Context) string {
+    return c.ClientIP()
+}
+
+// userKey menghitung batas per user yang sudah login
+func userKey(c *gin.Context) string {
+    if user, ok := c.Get(ContextUserKey); ok {
+        if u, ok := user.(models.User); ok {
+            return fmt.Sprintf("user:%d", u.ID)
+        }
+    }
+    return c.ClientIP()
+}
+
+func limitBy(limiters *keyedLimiter, key func(c *gin.Context) string) gin.HandlerFunc {
     return func(c *gin.Context) {
-        ip := c.ClientIP()
-        limiter := getVisitor(ip)
+        limiter := limiters.get(key(c))
 
         if !limiter.Allow() {
             c.JSON(http.StatusTooManyRequests, gin.H{
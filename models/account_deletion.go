@@ -0,0 +1,147 @@
+// models/account_deletion.go
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrOwnsResources is returned when an account still owns projects or teams and can therefore not be deleted
+var ErrOwnsResources = errors.New("user still owns projects or teams")
+
+// OwnedResources returns the projects and teams owned by the user. Ownership has to be transferred
+// before the account can be deleted, so that teammates keep their shared work.
+func OwnedResources(userID uint) ([]Project, []Team, error) {
+	var projects []Project
+	if err := DB.Select("id", "title").Where("owner_id = ?", userID).Find(&projects).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var teams []Team
+	if err := DB.Select("id", "name").Where("owner_id = ? AND deleted_at IS NULL", userID).Find(&teams).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return projects, teams, nil
+}
+
+// ScheduleAccountDeletion marks the account for anonymization at the given time
+func ScheduleAccountDeletion(userID uint, at time.Time) error {
+	return DB.Model(&User{}).Where("id = ?", userID).Update("deletion_scheduled_at", at).Error
+}
+
+// CancelAccountDeletion keeps an account that was scheduled for deletion
+func CancelAccountDeletion(userID uint) error {
+	return DB.Model(&User{}).Where("id = ?", userID).Update("deletion_scheduled_at", nil).Error
+}
+
+// AnonymizeUser removes the personal data of a user while keeping the notes, activities, tasks and files
+// they created, so projects shared with others stay intact. The account row remains with a placeholder
+// name and can no longer be used to log in. The avatar key the user had is returned, so the caller
+// can remove the images from storage.
+func AnonymizeUser(userID uint) (string, error) {
+	projects, teams, err := OwnedResources(userID)
+	if err != nil {
+		return "", err
+	}
+	if len(projects) > 0 || len(teams) > 0 {
+		return "", ErrOwnsResources
+	}
+
+	var user User
+	if err := DB.Select("id", "email", "avatar_key").First(&user, userID).Error; err != nil {
+		return "", err
+	}
+
+	if err := RevokeUserSessions(userID); err != nil {
+		return "", err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// Credentials, memberships and personal records. Unscoped so soft-deleted rows are removed as well.
+		personal := []interface{}{
+			&PersonalAccessToken{}, &RecoveryCode{}, &UserIdentity{}, &OAuthState{}, &PasswordHistory{},
+			&RefreshToken{}, &LoginAttempt{}, &Collaboration{}, &Notification{}, &EmailVerificationToken{}, &Token{},
+			&UserPreferences{},
+		}
+		for _, model := range personal {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{"team_members", "project_members"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE user_id = ?", userID).Error; err != nil {
+				return err
+			}
+		}
+
+		// Team invitations name the address of the user
+		if err := tx.Where("email = ?", strings.ToLower(user.Email)).Delete(&TeamInvitation{}).Error; err != nil {
+			return err
+		}
+
+		// Nobody is working on tasks that were assigned to the user anymore
+		if err := tx.Model(&Task{}).Where("assigned_to_id = ?", userID).Update("assigned_to_id", nil).Error; err != nil {
+			return err
+		}
+
+		// UpdateColumns skips the password hashing hook, the placeholder is not a valid bcrypt hash
+		// so no password ever matches it
+		return tx.Model(&User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+			"username":                fmt.Sprintf("deleted-user-%d", userID),
+			"email":                   fmt.Sprintf("deleted-user-%d@deleted.invalid", userID),
+			"pending_email":           "",
+			"avatar_key":              "",
+			"password":                "!",
+			"role":                    RoleMember,
+			"status":                  UserStatusDeleted,
+			"is_email_verified":       false,
+			"password_reset_required": false,
+			"deletion_scheduled_at":   nil,
+			"failed_login_attempts":   0,
+			"last_failed_login_at":    nil,
+			"locked_until":            nil,
+			"totp_secret":             "",
+			"totp_enabled":            false,
+			"totp_last_used_step":     0,
+			"updated_at":              time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return user.AvatarKey, nil
+}
+
+// PurgeDeletedAccounts anonymizes every account whose deletion grace period ended before now.
+// Accounts that still own projects or teams are skipped and reported in skipped.
+// removeAvatar is called with the avatar key of every anonymized account that had an avatar.
+func PurgeDeletedAccounts(now time.Time, removeAvatar func(avatarKey string)) (purged int, skipped []uint, err error) {
+	var userIDs []uint
+	if err := DB.Model(&User{}).
+		Where("deletion_scheduled_at <= ? AND status <> ?", now, UserStatusDeleted).
+		Pluck("id", &userIDs).Error; err != nil {
+		return 0, nil, err
+	}
+
+	for _, userID := range userIDs {
+		avatarKey, err := AnonymizeUser(userID)
+		if err != nil {
+			if errors.Is(err, ErrOwnsResources) {
+				skipped = append(skipped, userID)
+				continue
+			}
+			return purged, skipped, fmt.Errorf("failed to anonymize user %d: %w", userID, err)
+		}
+		if avatarKey != "" {
+			removeAvatar(avatarKey)
+		}
+		purged++
+	}
+
+	return purged, skipped, nil
+}
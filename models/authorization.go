@@ -30,10 +30,15 @@ func UserHasAccessToProject(userID uint, projectID uint) (bool, error) {
 	}
 
 	// Check if the user is a member of any team associated with the project
+	return UserIsMemberOfProjectTeams(userID, projectID)
+}
+
+// UserIsMemberOfProjectTeams checks if a user is a member of any team associated with a project.
+// Members of a sub-team count as members of the teams above it.
+func UserIsMemberOfProjectTeams(userID uint, projectID uint) (bool, error) {
 	var count int64
-	err = DB.Table("team_members").
-		Joins("JOIN project_teams ON team_members.team_id = project_teams.team_id").
-		Where("project_teams.project_id = ? AND team_members.user_id = ?", projectID, userID).
+	err := DB.Table("project_teams").
+		Where("project_id = ? AND team_id IN (?)", projectID, TeamIDsWithInheritedMembership(userID)).
 		Count(&count).Error
 	if err != nil {
 		return false, err
@@ -41,12 +46,11 @@ func UserHasAccessToProject(userID uint, projectID uint) (bool, error) {
 	return count > 0, nil
 }
 
-// UserIsMemberOfProjectTeams checks if a user is a member of any team associated with a project
-func UserIsMemberOfProjectTeams(userID uint, projectID uint) (bool, error) {
+// UserIsProjectCollaborator checks if a user collaborates on a project
+func UserIsProjectCollaborator(userID uint, projectID uint) (bool, error) {
 	var count int64
-	err := DB.Table("team_members").
-		Joins("JOIN project_teams ON team_members.team_id = project_teams.team_id").
-		Where("project_teams.project_id = ? AND team_members.user_id = ?", projectID, userID).
+	err := DB.Model(&Collaboration{}).
+		Where("project_id = ? AND user_id = ?", projectID, userID).
 		Count(&count).Error
 	if err != nil {
 		return false, err
@@ -92,6 +96,11 @@ func UserHasAccessToTeam(userID uint, teamID uint) (bool, error) {
 	return count > 0, nil
 }
 
+// TeamIDsOfUser returns a subquery selecting the IDs of the teams the user is a member of
+func TeamIDsOfUser(userID uint) *gorm.DB {
+	return DB.Model(&TeamMembership{}).Select("team_id").Where("user_id = ?", userID)
+}
+
 // UserIsTeamOwner checks if a user is the owner of a specific team
 func UserIsTeamOwner(userID uint, teamID uint) (bool, error) {
 	var count int64
@@ -113,3 +122,36 @@ func UserIsAdmin(userID uint) (bool, error) {
 	}
 	return user.Role == RoleAdmin, nil
 }
+
+// sharedWorkUsersSQL selects the users who share a team or a project with @user: members and owners of the
+// user's teams and the teams above them, and owners, collaborators and team members of the projects the user
+// takes part in. The members of a project team include those of its sub-teams.
+const sharedWorkUsersSQL = `
+WITH RECURSIVE user_teams(team_id) AS (
+	SELECT id FROM teams
+	WHERE deleted_at IS NULL AND (owner_id = @user OR id IN (SELECT team_id FROM team_members WHERE user_id = @user))
+	UNION
+	SELECT teams.parent_id FROM teams
+	JOIN user_teams ON teams.id = user_teams.team_id
+	WHERE teams.parent_id IS NOT NULL
+), user_projects AS (
+	SELECT id AS project_id FROM projects WHERE owner_id = @user
+	UNION SELECT project_id FROM collaborations WHERE user_id = @user
+	UNION SELECT project_id FROM project_teams WHERE team_id IN (SELECT team_id FROM user_teams)
+), project_team_tree(team_id) AS (
+	SELECT team_id FROM project_teams WHERE project_id IN (SELECT project_id FROM user_projects)
+	UNION
+	SELECT teams.id FROM teams
+	JOIN project_team_tree ON teams.parent_id = project_team_tree.team_id
+)
+SELECT user_id FROM team_members WHERE team_id IN (SELECT team_id FROM user_teams)
+UNION SELECT owner_id FROM teams WHERE id IN (SELECT team_id FROM user_teams)
+UNION SELECT owner_id FROM projects WHERE id IN (SELECT project_id FROM user_projects)
+UNION SELECT user_id FROM collaborations WHERE project_id IN (SELECT project_id FROM user_projects)
+UNION SELECT user_id FROM team_members WHERE team_id IN (SELECT team_id FROM project_team_tree)`
+
+// UsersSharingWorkWith returns a subquery selecting the IDs of the users who share a team or a project with the user,
+// for use as "id IN (?)"
+func UsersSharingWorkWith(userID uint) *gorm.DB {
+	return DB.Raw(sharedWorkUsersSQL, map[string]interface{}{"user": userID})
+}
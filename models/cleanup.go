@@ -0,0 +1,54 @@
+// models/cleanup.go
+package models
+
+import (
+	"time"
+)
+
+// PurgeExpiredRecords permanently deletes tokens, refresh tokens, sessions, OAuth states and signing keys
+// that expired before now, and login attempts older than loginAttemptRetention.
+// It returns the number of deleted rows per table.
+func PurgeExpiredRecords(now time.Time, loginAttemptRetention time.Duration) (map[string]int64, error) {
+	deleted := make(map[string]int64)
+
+	// Token rows are soft-deleted elsewhere, so Unscoped is needed to actually remove them
+	result := DB.Unscoped().
+		Where("expires_at < ? OR deleted_at IS NOT NULL", now).
+		Delete(&Token{})
+	if result.Error != nil {
+		return deleted, result.Error
+	}
+	deleted["tokens"] = result.RowsAffected
+
+	result = DB.Where("expires_at < ?", now).Delete(&RefreshToken{})
+	if result.Error != nil {
+		return deleted, result.Error
+	}
+	deleted["refresh_tokens"] = result.RowsAffected
+
+	result = DB.Where("expires_at < ?", now).Delete(&Session{})
+	if result.Error != nil {
+		return deleted, result.Error
+	}
+	deleted["sessions"] = result.RowsAffected
+
+	result = DB.Where("expires_at < ?", now).Delete(&OAuthState{})
+	if result.Error != nil {
+		return deleted, result.Error
+	}
+	deleted["oauth_states"] = result.RowsAffected
+
+	result = DB.Where("expires_at < ?", now).Delete(&SigningKey{})
+	if result.Error != nil {
+		return deleted, result.Error
+	}
+	deleted["signing_keys"] = result.RowsAffected
+
+	result = DB.Where("created_at < ?", now.Add(-loginAttemptRetention)).Delete(&LoginAttempt{})
+	if result.Error != nil {
+		return deleted, result.Error
+	}
+	deleted["login_attempts"] = result.RowsAffected
+
+	return deleted, nil
+}
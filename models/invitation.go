@@ -0,0 +1,72 @@
+// models/invitation.go
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation represents an invitation code created by an admin.
+// Registering with the code grants Role and, when scoped, joins the new user
+// to the given team or project.
+type Invitation struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	Code        string         `gorm:"type:varchar(64);uniqueIndex;not null" json:"code"`
+	Role        Role           `gorm:"type:varchar(50);not null" json:"role" validate:"required,oneof=admin manager member"`
+	TeamID      *uint          `gorm:"index" json:"team_id,omitempty"`
+	Team        *Team          `gorm:"foreignKey:TeamID;constraint:OnDelete:SET NULL" json:"team,omitempty"`
+	ProjectID   *uint          `gorm:"index" json:"project_id,omitempty"`
+	Project     *Project       `gorm:"foreignKey:ProjectID;constraint:OnDelete:SET NULL" json:"project,omitempty"`
+	CreatedByID *uint          `gorm:"index" json:"created_by_id,omitempty"` // Nil for the bootstrap invitation
+	CreatedBy   *User          `gorm:"foreignKey:CreatedByID" json:"created_by,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	MaxUses     int            `gorm:"not null" json:"max_uses"` // 0 means unlimited
+	UseCount    int            `gorm:"not null;default:0" json:"use_count"`
+	RevokedAt   *time.Time     `json:"revoked_at,omitempty"`
+}
+
+// IsUsable reports whether the invitation can still be redeemed
+func (i *Invitation) IsUsable(now time.Time) bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(now) {
+		return false
+	}
+	return i.MaxUses == 0 || i.UseCount < i.MaxUses
+}
+
+// BeforeCreate GORM hook untuk validasi sebelum membuat undangan baru
+func (i *Invitation) BeforeCreate(tx *gorm.DB) (err error) {
+	return validate.Struct(i)
+}
+
+// EnsureBootstrapInvitation creates a single-use admin invitation with the given code
+// when no admin account exists yet, so the first admin can register.
+func EnsureBootstrapInvitation(code string) error {
+	if code == "" {
+		return nil
+	}
+
+	var admins int64
+	if err := DB.Model(&User{}).Where("role = ?", RoleAdmin).Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+
+	var existing int64
+	if err := DB.Model(&Invitation{}).Where("code = ?", code).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	return DB.Create(&Invitation{Code: code, Role: RoleAdmin, MaxUses: 1}).Error
+}
@@ -0,0 +1,25 @@
+// models/login_attempt.go
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt records a single login attempt, used to throttle attempts per IP address
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Email     string    `gorm:"type:varchar(255);index" json:"email"`
+	IPAddress string    `gorm:"type:varchar(64);index" json:"ip_address"`
+	UserID    *uint     `gorm:"index" json:"user_id,omitempty"`
+	Success   bool      `gorm:"not null;default:false" json:"success"`
+}
+
+// CountFailedLoginAttemptsByIP counts failed login attempts from an IP address since the given time
+func CountFailedLoginAttemptsByIP(ip string, since time.Time) (int64, error) {
+	var count int64
+	err := DB.Model(&LoginAttempt{}).
+		Where("ip_address = ? AND success = ? AND created_at > ?", ip, false, since).
+		Count(&count).Error
+	return count, err
+}
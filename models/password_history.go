@@ -0,0 +1,48 @@
+// models/password_history.go
+package models
+
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// PasswordHistory stores the bcrypt hashes of previous passwords of a user, to prevent reuse
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	User         *User     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+}
+
+// RecordPasswordHistory stores the hash of a newly set password and deletes history beyond the last keep entries
+func RecordPasswordHistory(tx *gorm.DB, userID uint, passwordHash string, keep int) error {
+	if err := tx.Create(&PasswordHistory{UserID: userID, PasswordHash: passwordHash}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("user_id = ? AND id NOT IN (?)", userID,
+		tx.Model(&PasswordHistory{}).Select("id").Where("user_id = ?", userID).Order("id desc").Limit(keep)).
+		Delete(&PasswordHistory{}).Error
+}
+
+// PasswordUsedRecently reports whether password matches one of the last n passwords of the user
+func PasswordUsedRecently(userID uint, password string, n int) (bool, error) {
+	if n <= 0 {
+		return false, nil
+	}
+
+	var history []PasswordHistory
+	if err := DB.Where("user_id = ?", userID).Order("id desc").Limit(n).Find(&history).Error; err != nil {
+		return false, err
+	}
+
+	for _, entry := range history {
+		if bcrypt.CompareHashAndPassword([]byte(entry.PasswordHash), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,251 @@
+// models/permission.go
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Permission names an action that the permission matrix can allow or deny
+type Permission string
+
+// Global permissions depend only on the role of the user
+const (
+	PermissionProjectCreate    Permission = "project.create"
+	PermissionTeamCreate       Permission = "team.create"
+	PermissionUserManage       Permission = "user.manage"
+	PermissionInvitationManage Permission = "invitation.manage"
+)
+
+// Project permissions depend on how the user is related to the project
+const (
+	PermissionProjectView                Permission = "project.view"
+	PermissionProjectUpdate              Permission = "project.update"
+	PermissionProjectDelete              Permission = "project.delete"
+	PermissionProjectTransfer            Permission = "project.transfer"
+	PermissionProjectManageCollaborators Permission = "project.manage_collaborators"
+	PermissionProjectManageTeams         Permission = "project.manage_teams"
+
+	PermissionTaskView   Permission = "task.view"
+	PermissionTaskCreate Permission = "task.create"
+	PermissionTaskUpdate Permission = "task.update"
+	PermissionTaskDelete Permission = "task.delete"
+	// PermissionTaskAssign allows assigning tasks to other users; everyone who can edit tasks may assign them to themselves
+	PermissionTaskAssign Permission = "task.assign"
+
+	PermissionActivityView   Permission = "activity.view"
+	PermissionActivityCreate Permission = "activity.create"
+	PermissionActivityUpdate Permission = "activity.update"
+	PermissionActivityDelete Permission = "activity.delete"
+
+	PermissionNoteView   Permission = "note.view"
+	PermissionNoteCreate Permission = "note.create"
+	PermissionNoteUpdate Permission = "note.update"
+	PermissionNoteDelete Permission = "note.delete"
+
+	PermissionFileView   Permission = "file.view"
+	PermissionFileUpload Permission = "file.upload"
+	// PermissionFileDelete allows deleting the files the user uploaded, PermissionFileManage allows deleting any file
+	PermissionFileDelete Permission = "file.delete"
+	PermissionFileManage Permission = "file.manage"
+
+	PermissionNotificationView   Permission = "notification.view"
+	PermissionNotificationCreate Permission = "notification.create"
+	PermissionNotificationUpdate Permission = "notification.update"
+	PermissionNotificationDelete Permission = "notification.delete"
+)
+
+// Team permissions depend on how the user is related to the team
+const (
+	PermissionTeamView          Permission = "team.view"
+	PermissionTeamUpdate        Permission = "team.update"
+	PermissionTeamDelete        Permission = "team.delete"
+	PermissionTeamTransfer      Permission = "team.transfer"
+	PermissionTeamManageMembers Permission = "team.manage_members"
+	// PermissionTeamManageRoles allows promoting members to maintainer and demoting maintainers
+	PermissionTeamManageRoles Permission = "team.manage_roles"
+)
+
+// ProjectRelation describes how a user takes part in a project
+type ProjectRelation string
+
+const (
+	ProjectRelationOwner ProjectRelation = "owner"
+	// ProjectRelationAdmin is a collaborator with the admin collaboration role
+	ProjectRelationAdmin        ProjectRelation = "admin"
+	ProjectRelationCollaborator ProjectRelation = "collaborator"
+	// ProjectRelationTeamMember is a member of one of the teams working on the project
+	ProjectRelationTeamMember ProjectRelation = "team_member"
+)
+
+// TeamRelation describes how a user takes part in a team
+type TeamRelation string
+
+const (
+	TeamRelationOwner      TeamRelation = "owner"
+	TeamRelationMaintainer TeamRelation = "maintainer"
+	TeamRelationMember     TeamRelation = "member"
+)
+
+// contributorPermissions are granted to everyone working on a project
+var contributorPermissions = []Permission{
+	PermissionProjectView,
+	PermissionTaskView, PermissionTaskCreate, PermissionTaskUpdate,
+	PermissionActivityView, PermissionActivityCreate, PermissionActivityUpdate,
+	PermissionNoteView, PermissionNoteCreate, PermissionNoteUpdate,
+	PermissionFileView, PermissionFileUpload, PermissionFileDelete,
+	PermissionNotificationView, PermissionNotificationCreate, PermissionNotificationUpdate, PermissionNotificationDelete,
+}
+
+// projectAdminPermissions are granted to project owners and admin collaborators on top of contributorPermissions
+var projectAdminPermissions = []Permission{
+	PermissionProjectUpdate, PermissionProjectManageCollaborators, PermissionProjectManageTeams,
+	PermissionTaskDelete, PermissionTaskAssign,
+	PermissionActivityDelete,
+	PermissionNoteDelete,
+	PermissionFileManage,
+}
+
+// rolePermissions is the permission matrix for global roles. Admins are not listed, they have every permission.
+var rolePermissions = map[Role][]Permission{
+	RoleManager: {PermissionProjectCreate, PermissionTeamCreate},
+	RoleMember:  {PermissionProjectCreate, PermissionTeamCreate},
+}
+
+// projectPermissions is the permission matrix for project relations
+var projectPermissions = map[ProjectRelation][]Permission{
+	ProjectRelationOwner:        concatPermissions(contributorPermissions, projectAdminPermissions, []Permission{PermissionProjectDelete, PermissionProjectTransfer}),
+	ProjectRelationAdmin:        concatPermissions(contributorPermissions, projectAdminPermissions),
+	ProjectRelationCollaborator: contributorPermissions,
+	ProjectRelationTeamMember:   contributorPermissions,
+}
+
+// teamPermissions is the permission matrix for team relations
+var teamPermissions = map[TeamRelation][]Permission{
+	TeamRelationOwner: {
+		PermissionTeamView, PermissionTeamUpdate, PermissionTeamDelete, PermissionTeamTransfer,
+		PermissionTeamManageMembers, PermissionTeamManageRoles,
+	},
+	TeamRelationMaintainer: {PermissionTeamView, PermissionTeamUpdate, PermissionTeamManageMembers},
+	TeamRelationMember:     {PermissionTeamView},
+}
+
+// PermissionSet holds the permissions a user has for one request
+type PermissionSet struct {
+	all         bool
+	permissions map[Permission]bool
+}
+
+// Has reports whether the set contains the permission
+func (s PermissionSet) Has(permission Permission) bool {
+	return s.all || s.permissions[permission]
+}
+
+// add grants the permissions to the set
+func (s *PermissionSet) add(permissions []Permission) {
+	if s.permissions == nil {
+		s.permissions = make(map[Permission]bool)
+	}
+	for _, permission := range permissions {
+		s.permissions[permission] = true
+	}
+}
+
+// GlobalPermissions returns the permissions the user has through the global role
+func GlobalPermissions(user User) PermissionSet {
+	var set PermissionSet
+	if user.Role == RoleAdmin {
+		set.all = true
+		return set
+	}
+	set.add(rolePermissions[user.Role])
+	return set
+}
+
+// ProjectPermissions returns the permissions the user has for a project, including those of the global role.
+// It returns gorm.ErrRecordNotFound when the project does not exist.
+func ProjectPermissions(user User, projectID uint) (PermissionSet, error) {
+	set := GlobalPermissions(user)
+
+	var project Project
+	if err := DB.Select("id", "owner_id").First(&project, projectID).Error; err != nil {
+		return PermissionSet{}, err
+	}
+	if set.all {
+		return set, nil
+	}
+
+	relations, err := userProjectRelations(user.ID, project)
+	if err != nil {
+		return PermissionSet{}, err
+	}
+	for _, relation := range relations {
+		set.add(projectPermissions[relation])
+	}
+	return set, nil
+}
+
+// TeamPermissions returns the permissions the user has for a team, including those of the global role.
+// It returns gorm.ErrRecordNotFound when the team does not exist.
+func TeamPermissions(user User, teamID uint) (PermissionSet, error) {
+	set := GlobalPermissions(user)
+
+	var team Team
+	if err := DB.Select("id", "owner_id").First(&team, teamID).Error; err != nil {
+		return PermissionSet{}, err
+	}
+	if set.all {
+		return set, nil
+	}
+
+	if team.OwnerID == user.ID {
+		set.add(teamPermissions[TeamRelationOwner])
+	}
+	membership, err := GetTeamMembership(teamID, user.ID)
+	switch {
+	case err == nil && membership.Role == TeamRoleMaintainer:
+		set.add(teamPermissions[TeamRelationMaintainer])
+		set.add(teamPermissions[TeamRelationMember])
+	case err == nil:
+		set.add(teamPermissions[TeamRelationMember])
+	case err != gorm.ErrRecordNotFound:
+		return PermissionSet{}, err
+	}
+	return set, nil
+}
+
+// userProjectRelations lists every way the user takes part in the project
+func userProjectRelations(userID uint, project Project) ([]ProjectRelation, error) {
+	var relations []ProjectRelation
+	if project.OwnerID == userID {
+		relations = append(relations, ProjectRelationOwner)
+	}
+
+	var collaboration Collaboration
+	err := DB.Where("project_id = ? AND user_id = ?", project.ID, userID).First(&collaboration).Error
+	switch {
+	case err == nil && collaboration.Role == CollaborationRoleAdmin:
+		relations = append(relations, ProjectRelationAdmin)
+	case err == nil:
+		relations = append(relations, ProjectRelationCollaborator)
+	case err != gorm.ErrRecordNotFound:
+		return nil, err
+	}
+
+	isMember, err := UserIsMemberOfProjectTeams(userID, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		relations = append(relations, ProjectRelationTeamMember)
+	}
+	return relations, nil
+}
+
+// concatPermissions joins permission lists into a new slice
+func concatPermissions(lists ...[]Permission) []Permission {
+	var joined []Permission
+	for _, list := range lists {
+		joined = append(joined, list...)
+	}
+	return joined
+}
@@ -0,0 +1,83 @@
+// models/personal_access_token.go
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token so leaked tokens are easy to recognise
+const PersonalAccessTokenPrefix = "aurpat_"
+
+// PersonalAccessTokenResources lists the resources a personal access token can be scoped to.
+// Each resource has a ":read" and a ":write" scope; write implies read.
+// Account and admin routes are deliberately missing: they are only reachable with a login session.
+var PersonalAccessTokenResources = []string{
+	"profile",
+	"teams",
+	"projects",
+	"activities",
+	"tasks",
+	"notes",
+	"files",
+	"notifications",
+}
+
+// PersonalAccessToken represents a named, long-lived API token created by a user for automation.
+// Only the SHA-256 digest of the token is stored.
+type PersonalAccessToken struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	UserID      uint           `gorm:"not null;index" json:"user_id"`
+	User        *User          `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
+	TokenPrefix string         `gorm:"type:varchar(16);not null" json:"token_prefix"` // First characters of the token, for display
+	TokenHash   string         `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Scopes      pq.StringArray `gorm:"type:text[];not null" json:"scopes"`
+	ProjectID   *uint          `gorm:"index" json:"project_id,omitempty"` // When set the token only works for this project
+	Project     *Project       `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"project,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time     `json:"last_used_at,omitempty"`
+	LastUsedIP  string         `gorm:"type:varchar(64)" json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time     `json:"revoked_at,omitempty"`
+}
+
+// IsActive reports whether the token has not been revoked and has not expired
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || t.ExpiresAt.After(now))
+}
+
+// HasScope reports whether the token grants the scope. A ":write" scope also grants ":read".
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	resource, action, _ := strings.Cut(scope, ":")
+	for _, granted := range t.Scopes {
+		if granted == scope || (action == "read" && granted == resource+":write") {
+			return true
+		}
+	}
+	return false
+}
+
+// RevokeUserAccessTokens revokes every personal access token of the user
+func RevokeUserAccessTokens(userID uint) error {
+	return DB.Model(&PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// IsValidPersonalAccessTokenScope reports whether scope is a grantable "<resource>:<read|write>" scope
+func IsValidPersonalAccessTokenScope(scope string) bool {
+	resource, action, ok := strings.Cut(scope, ":")
+	if !ok || (action != "read" && action != "write") {
+		return false
+	}
+	for _, r := range PersonalAccessTokenResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
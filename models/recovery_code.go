@@ -0,0 +1,17 @@
+// models/recovery_code.go
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode represents a one-time two-factor recovery code.
+// Only the SHA-256 digest of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	User      *User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	CodeHash  string     `gorm:"type:varchar(64);not null;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
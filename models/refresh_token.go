@@ -0,0 +1,46 @@
+// models/refresh_token.go
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken represents an opaque refresh token issued at login.
+// Only the SHA-256 digest of the token is stored. Tokens obtained from the same
+// login share a FamilyID, which is the ID of the login's Session; every rotation marks the old token as used, so presenting
+// a used token again is treated as theft and revokes the whole family.
+type RefreshToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	User       *User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	FamilyID   string     `gorm:"type:varchar(64);not null;index" json:"family_id"`
+	TokenHash  string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy *uint      `json:"replaced_by,omitempty"`
+}
+
+// IsActive reports whether the refresh token can still be exchanged
+func (r *RefreshToken) IsActive(now time.Time) bool {
+	return r.UsedAt == nil && r.RevokedAt == nil && r.ExpiresAt.After(now)
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token that belongs to the given family,
+// and the session the family was issued for
+func RevokeRefreshTokenFamily(familyID string) error {
+	if _, err := revokeSessions(func(db *gorm.DB) *gorm.DB {
+		return db.Where("id = ?", familyID)
+	}); err != nil {
+		return err
+	}
+
+	// Families issued before sessions existed have no session row
+	return DB.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
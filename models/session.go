@@ -0,0 +1,137 @@
+// models/session.go
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/mfuadfakhruzzaki/backendaurauran/cache"
+	"gorm.io/gorm"
+)
+
+// Revocations caches the IDs of revoked sessions so that authenticated requests can be
+// checked without a database query. It is replaced in main according to the configuration.
+var Revocations cache.RevocationStore = cache.NewMemoryRevocationStore()
+
+// Session represents one login of a user on a device. The session ID is the jti of every
+// access token issued for the login and the FamilyID of its refresh tokens, so revoking
+// the session invalidates both.
+type Session struct {
+	ID         string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	User       *User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	UserAgent  string     `gorm:"type:varchar(512)" json:"user_agent"`
+	IPAddress  string     `gorm:"type:varchar(64)" json:"ip_address"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Current    bool       `gorm:"-" json:"current"` // Set when listing sessions for the requesting session
+}
+
+// IsActive reports whether the session has not been revoked and has not expired
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
+
+// RevokeSession revokes a single session of the user together with its refresh tokens.
+// It returns gorm.ErrRecordNotFound if the user has no active session with that ID.
+func RevokeSession(userID uint, sessionID string) error {
+	revoked, err := revokeSessions(func(db *gorm.DB) *gorm.DB {
+		return db.Where("id = ? AND user_id = ?", sessionID, userID)
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every session of the user and all of their refresh tokens
+func RevokeUserSessions(userID uint) error {
+	return RevokeOtherUserSessions(userID, "")
+}
+
+// RevokeOtherUserSessions revokes every session of the user except keepSessionID, together with
+// their refresh tokens. An empty keepSessionID revokes all of them.
+func RevokeOtherUserSessions(userID uint, keepSessionID string) error {
+	if _, err := revokeSessions(func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ? AND id <> ?", userID, keepSessionID)
+	}); err != nil {
+		return err
+	}
+
+	// Also catch refresh tokens issued before sessions existed
+	return DB.Model(&RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeSessions revokes the active sessions matched by scope together with their refresh
+// tokens, then records them in Revocations so AuthMiddleware rejects their access tokens.
+// It returns the number of sessions revoked.
+func revokeSessions(scope func(*gorm.DB) *gorm.DB) (int, error) {
+	var sessions []Session
+	now := time.Now()
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(scope).Where("revoked_at IS NULL").Find(&sessions).Error; err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(sessions))
+		for i, session := range sessions {
+			ids[i] = session.ID
+		}
+
+		if err := tx.Model(&Session{}).Where("id IN ?", ids).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&RefreshToken{}).
+			Where("family_id IN ? AND revoked_at IS NULL", ids).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		if err := Revocations.Revoke(context.Background(), session.ID, session.ExpiresAt); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(sessions), nil
+}
+
+// revocationSyncOverlap is subtracted from the next sync start, because revoked_at is set
+// before the revoking transaction commits and may therefore become visible late
+const revocationSyncOverlap = time.Minute
+
+// SyncRevokedSessions copies sessions revoked at or after since, and not yet expired, into
+// Revocations. It is used to warm the store at startup and to pick up revocations made by
+// other instances. The returned time should be passed as since on the next call.
+func SyncRevokedSessions(since time.Time) (time.Time, error) {
+	now := time.Now()
+
+	var sessions []Session
+	if err := DB.Select("id", "expires_at").
+		Where("revoked_at IS NOT NULL AND revoked_at >= ? AND expires_at > ?", since, now).
+		Find(&sessions).Error; err != nil {
+		return since, err
+	}
+
+	for _, session := range sessions {
+		if err := Revocations.Revoke(context.Background(), session.ID, session.ExpiresAt); err != nil {
+			return since, err
+		}
+	}
+
+	return now.Add(-revocationSyncOverlap), nil
+}
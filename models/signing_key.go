@@ -0,0 +1,28 @@
+// models/signing_key.go
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SigningKey represents an asymmetric key used to sign access tokens.
+// The newest active key that is not retired signs new tokens. New keys are published in the JWKS
+// before they become active, and retired keys stay published until every token signed with them has expired.
+type SigningKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+	Kid         string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"kid"`
+	Algorithm   string     `gorm:"type:varchar(16);not null" json:"algorithm" validate:"required,oneof=RS256 EdDSA"`
+	PrivateKey  string     `gorm:"type:text;not null" json:"-"`       // PKCS#8, encrypted with JWT_SECRET
+	PublicKey   string     `gorm:"type:text;not null" json:"-"`       // PKIX PEM
+	ActivatesAt *time.Time `json:"activates_at,omitempty"`            // Not used for signing before this time
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`              // No longer used for signing from this time
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at,omitempty"` // No longer accepted for verification
+}
+
+// BeforeCreate GORM hook untuk validasi sebelum menyimpan kunci baru
+func (k *SigningKey) BeforeCreate(tx *gorm.DB) (err error) {
+	return validate.Struct(k)
+}
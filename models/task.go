@@ -45,13 +45,34 @@ type Task struct {
 
 // BeforeCreate GORM hook to validate before creating a new task
 func (t *Task) BeforeCreate(tx *gorm.DB) (err error) {
-	// Implementasi validasi tambahan jika diperlukan
+	t.normalizeDeadline()
 	return
 }
 
 // BeforeUpdate GORM hook to validate before updating a task
 func (t *Task) BeforeUpdate(tx *gorm.DB) (err error) {
-	// Implementasi validasi tambahan jika diperlukan
+	t.normalizeDeadline()
 	return
 }
 
+// normalizeDeadline converts the deadline to UTC. The column has no time zone, so without this
+// the wall clock of whatever offset the client sent would be stored and read back as UTC.
+func (t *Task) normalizeDeadline() {
+	if t.Deadline != nil {
+		deadline := t.Deadline.UTC()
+		t.Deadline = &deadline
+	}
+}
+
+// TasksDueBetween limits a task query to open tasks with a deadline in [from, to)
+func TasksDueBetween(query *gorm.DB, from, to time.Time) *gorm.DB {
+	return query.Where("deadline >= ? AND deadline < ?", from.UTC(), to.UTC()).
+		Where("status NOT IN ?", []TaskStatus{TaskStatusCompleted, TaskStatusCancelled})
+}
+
+// TasksOverdue limits a task query to open tasks whose deadline has passed
+func TasksOverdue(query *gorm.DB, now time.Time) *gorm.DB {
+	return query.Where("deadline < ?", now.UTC()).
+		Where("status NOT IN ?", []TaskStatus{TaskStatusCompleted, TaskStatusCancelled})
+}
+
@@ -9,6 +9,8 @@ type Team struct {
     ID          uint       `gorm:"primaryKey" json:"id"`
     Name        string     `gorm:"not null" json:"name"`
     Description string     `json:"description"`
+    ParentID    *uint      `gorm:"index" json:"parent_id"` // Department the team belongs to; its members may work on the parent's projects
+    SubTeams    []Team     `gorm:"foreignKey:ParentID;constraint:OnDelete:SET NULL" json:"sub_teams,omitempty"`
     OwnerID     uint       `gorm:"not null;index" json:"owner_id"`
     Owner       User       `gorm:"foreignKey:OwnerID" json:"owner"`
     Members     []User     `gorm:"many2many:team_members;constraint:OnDelete:CASCADE" json:"members,omitempty"`
@@ -0,0 +1,73 @@
+// models/team_hierarchy.go
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrTeamCycle is returned when a team would become its own ancestor
+var ErrTeamCycle = errors.New("a team cannot be placed below itself or one of its sub-teams")
+
+// teamHierarchyLockKey identifies the advisory lock held while a team is moved in the hierarchy
+const teamHierarchyLockKey = 7_302_001
+
+// inheritedTeamsSQL selects the teams the user is a member of together with all their ancestors.
+// A member of a sub-team counts as a member of every team above it, so they can work on the projects
+// of the whole department. UNION instead of UNION ALL stops the recursion on rows it has already seen.
+const inheritedTeamsSQL = `
+WITH RECURSIVE inherited_teams(id) AS (
+	SELECT team_id FROM team_members WHERE user_id = ?
+	UNION
+	SELECT teams.parent_id FROM teams
+	JOIN inherited_teams ON teams.id = inherited_teams.id
+	WHERE teams.parent_id IS NOT NULL
+)
+SELECT id FROM inherited_teams`
+
+// teamAncestorsSQL counts how often the second team appears among the first team and its ancestors
+const teamAncestorsSQL = `
+WITH RECURSIVE ancestors(id) AS (
+	SELECT id FROM teams WHERE id = ?
+	UNION
+	SELECT teams.parent_id FROM teams
+	JOIN ancestors ON teams.id = ancestors.id
+	WHERE teams.parent_id IS NOT NULL
+)
+SELECT COUNT(*) FROM ancestors WHERE id = ?`
+
+// TeamIDsWithInheritedMembership returns a subquery selecting the IDs of the teams the user is a member of,
+// directly or through one of their sub-teams, for use as "team_id IN (?)"
+func TeamIDsWithInheritedMembership(userID uint) *gorm.DB {
+	return DB.Raw(inheritedTeamsSQL, userID)
+}
+
+// ProjectIDsOfUserTeams returns a subquery selecting the IDs of the projects attached to a team the user
+// is a member of, directly or through one of their sub-teams
+func ProjectIDsOfUserTeams(userID uint) *gorm.DB {
+	return DB.Table("project_teams").Select("project_id").Where("team_id IN (?)", TeamIDsWithInheritedMembership(userID))
+}
+
+// SetTeamParent places a team below parentID, or makes it a top-level team when parentID is nil.
+// It returns ErrTeamCycle when the parent is the team itself or one of its sub-teams.
+func SetTeamParent(teamID uint, parentID *uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// Moves are serialized, two concurrent moves could otherwise each pass the check and form a cycle together
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", teamHierarchyLockKey).Error; err != nil {
+			return err
+		}
+
+		if parentID != nil {
+			var count int64
+			if err := tx.Raw(teamAncestorsSQL, *parentID, teamID).Scan(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrTeamCycle
+			}
+		}
+
+		return tx.Model(&Team{}).Where("id = ?", teamID).Update("parent_id", parentID).Error
+	})
+}
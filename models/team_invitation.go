@@ -0,0 +1,56 @@
+// models/team_invitation.go
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TeamInvitationStatus represents the state of a team invitation
+type TeamInvitationStatus string
+
+const (
+	TeamInvitationPending  TeamInvitationStatus = "pending"
+	TeamInvitationAccepted TeamInvitationStatus = "accepted"
+	TeamInvitationDeclined TeamInvitationStatus = "declined"
+	TeamInvitationRevoked  TeamInvitationStatus = "revoked"
+)
+
+// TeamInvitation invites an email address to join a team. The address does not need to belong to
+// a registered user yet; the invitation is accepted with the token sent by email, or from the
+// invitation list of the user who owns the (verified) address.
+type TeamInvitation struct {
+	ID          uint                 `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	TeamID      uint                 `gorm:"not null;index" json:"team_id"`
+	Team        *Team                `gorm:"foreignKey:TeamID;constraint:OnDelete:CASCADE" json:"team,omitempty"`
+	Email       string               `gorm:"type:varchar(255);not null;index" json:"email"` // Stored in lower case
+	Role        TeamRole             `gorm:"type:varchar(20);not null" json:"role"`
+	TokenHash   string               `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"` // SHA-256 of the token sent by email
+	Status      TeamInvitationStatus `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	InvitedByID *uint                `gorm:"index" json:"invited_by_id,omitempty"`
+	InvitedBy   *User                `gorm:"foreignKey:InvitedByID;constraint:OnDelete:SET NULL" json:"invited_by,omitempty"`
+	ExpiresAt   time.Time            `gorm:"not null" json:"expires_at"`
+	RespondedAt *time.Time           `json:"responded_at,omitempty"` // When the invitation was accepted, declined or revoked
+}
+
+// IsOpen reports whether the invitation can still be accepted or declined
+func (i *TeamInvitation) IsOpen(now time.Time) bool {
+	return i.Status == TeamInvitationPending && i.ExpiresAt.After(now)
+}
+
+// OpenTeamInvitations limits a query to invitations that can still be accepted or declined
+func OpenTeamInvitations(query *gorm.DB, now time.Time) *gorm.DB {
+	return query.Where("team_invitations.status = ? AND team_invitations.expires_at > ?", TeamInvitationPending, now)
+}
+
+// CloseTeamInvitation marks an open invitation as accepted, declined or revoked. It returns false when the
+// invitation was closed or expired in the meantime, so concurrent responses are handled only once.
+func CloseTeamInvitation(tx *gorm.DB, invitationID uint, status TeamInvitationStatus, now time.Time) (bool, error) {
+	result := OpenTeamInvitations(tx.Model(&TeamInvitation{}), now).
+		Where("id = ?", invitationID).
+		Updates(map[string]interface{}{"status": status, "responded_at": now})
+	return result.RowsAffected == 1, result.Error
+}
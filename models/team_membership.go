@@ -0,0 +1,76 @@
+// models/team_membership.go
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TeamRole is the role of a member within a team
+type TeamRole string
+
+const (
+	TeamRoleOwner      TeamRole = "owner" // Held only by Team.OwnerID, changed through an ownership transfer
+	TeamRoleMaintainer TeamRole = "maintainer"
+	TeamRoleMember     TeamRole = "member"
+)
+
+// TeamMembership is the join table behind Team.Members and User.Teams
+type TeamMembership struct {
+	TeamID   uint      `gorm:"primaryKey;autoIncrement:false" json:"team_id"`
+	UserID   uint      `gorm:"primaryKey;autoIncrement:false;index" json:"user_id"`
+	User     *User     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Role     TeamRole  `gorm:"type:varchar(20);not null;default:member" json:"role"`
+	JoinedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"joined_at"`
+}
+
+// TableName keeps the table name of the former many2many join table
+func (TeamMembership) TableName() string {
+	return "team_members"
+}
+
+// BeforeCreate fills in the role and join time of members added through the Members association
+func (m *TeamMembership) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.Role == "" {
+		m.Role = TeamRoleMember
+	}
+	if m.JoinedAt.IsZero() {
+		m.JoinedAt = time.Now()
+	}
+	return
+}
+
+// SetupTeamMembership registers TeamMembership as the join table of the team member associations.
+// It has to run before the migrations.
+func SetupTeamMembership(db *gorm.DB) error {
+	if err := db.SetupJoinTable(&Team{}, "Members", &TeamMembership{}); err != nil {
+		return err
+	}
+	return db.SetupJoinTable(&User{}, "Teams", &TeamMembership{})
+}
+
+// EnsureTeamOwnerMemberships gives every team owner a membership with the owner role. Owners used to be
+// kept only in Team.OwnerID, and memberships added before roles existed default to the member role.
+func EnsureTeamOwnerMemberships(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO team_members (team_id, user_id, role, joined_at)
+	SELECT id, owner_id, ?, created_at FROM teams WHERE deleted_at IS NULL
+	ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role WHERE team_members.role <> EXCLUDED.role`, TeamRoleOwner).Error
+}
+
+// GetTeamMembership returns the membership of a user in a team.
+// It returns gorm.ErrRecordNotFound when the user is not a member.
+func GetTeamMembership(teamID, userID uint) (TeamMembership, error) {
+	var membership TeamMembership
+	err := DB.Where("team_id = ? AND user_id = ?", teamID, userID).First(&membership).Error
+	return membership, err
+}
+
+// SetTeamMemberRole adds the user to the team with the role, or changes the role of an existing member
+func SetTeamMemberRole(tx *gorm.DB, teamID, userID uint, role TeamRole) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "team_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(&TeamMembership{TeamID: teamID, UserID: userID, Role: role}).Error
+}
@@ -2,6 +2,7 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -23,9 +24,16 @@ const (
     TokenTypePasswordReset TokenType = "password_reset"
     TokenTypeEmailVerify   TokenType = "email_verify"
     TokenTypeJWTBlacklist  TokenType = "jwt_blacklist" // New type for blacklist
+    TokenTypeAccountUnlock TokenType = "account_unlock"
+    TokenTypeMFAChallenge  TokenType = "mfa_challenge"
+    TokenTypeEmailChange   TokenType = "email_change"
+    TokenTypeMagicLogin    TokenType = "magic_login"
 )
 
-// Token represents the token model
+// ErrTokenInvalid is returned when a token does not exist, has expired or has already been used
+var ErrTokenInvalid = errors.New("invalid or expired token")
+
+// Token represents the token model. Only the SHA-256 digest of the token sent to the user is stored.
 type Token struct {
     ID        uint           `gorm:"primaryKey" json:"id"`
     CreatedAt time.Time      `json:"created_at"`
@@ -33,8 +41,8 @@ type Token struct {
     DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
     UserID    uint           `gorm:"not null;index" json:"user_id" validate:"required"`
     User      *User          `gorm:"foreignKey:UserID" json:"user,omitempty"`
-    Token     string         `gorm:"type:varchar(512);uniqueIndex;not null" json:"token" validate:"required"`
-    Type      TokenType      `gorm:"type:varchar(20);not null" json:"type" validate:"required,oneof=password_reset email_verify jwt_blacklist"`
+    TokenHash string         `gorm:"type:varchar(64);uniqueIndex" json:"-" validate:"required"`
+    Type      TokenType      `gorm:"type:varchar(20);not null" json:"type" validate:"required,oneof=password_reset email_verify jwt_blacklist account_unlock mfa_challenge email_change magic_login"`
     ExpiresAt time.Time      `gorm:"not null" json:"expires_at" validate:"required,gtfield=CreatedAt"`
 }
 
@@ -79,3 +87,69 @@ func (t *Token) BeforeUpdate(tx *gorm.DB) (err error) {
 
     return nil
 }
+
+// IssueToken stores the digest of a new token for the user and deletes the earlier tokens of the same type,
+// so only the most recently sent link or code can be used
+func IssueToken(tx *gorm.DB, userID uint, tokenType TokenType, tokenHash string, expiresAt time.Time) error {
+    if err := tx.Where("user_id = ? AND type = ?", userID, tokenType).Delete(&Token{}).Error; err != nil {
+        return err
+    }
+
+    return tx.Create(&Token{
+        UserID:    userID,
+        TokenHash: tokenHash,
+        Type:      tokenType,
+        ExpiresAt: expiresAt,
+    }).Error
+}
+
+// FindValidToken looks up an unexpired token by its digest and type without using it up.
+// It returns ErrTokenInvalid when no such token exists.
+func FindValidToken(tx *gorm.DB, tokenHash string, tokenType TokenType) (*Token, error) {
+    var token Token
+    err := tx.Where("token_hash = ? AND type = ? AND expires_at > ?", tokenHash, tokenType, time.Now()).First(&token).Error
+    if err == gorm.ErrRecordNotFound {
+        return nil, ErrTokenInvalid
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &token, nil
+}
+
+// ConsumeToken looks up an unexpired token by its digest and type and deletes it, so it can only be used once.
+// When two requests present the same token at the same time only one of them succeeds; the other gets ErrTokenInvalid.
+func ConsumeToken(tx *gorm.DB, tokenHash string, tokenType TokenType) (*Token, error) {
+    token, err := FindValidToken(tx, tokenHash, tokenType)
+    if err != nil {
+        return nil, err
+    }
+
+    result := tx.Delete(token)
+    if result.Error != nil {
+        return nil, result.Error
+    }
+    if result.RowsAffected == 0 {
+        return nil, ErrTokenInvalid
+    }
+    return token, nil
+}
+
+// MigrateTokenHashes replaces the plaintext tokens of databases created before tokens were hashed.
+// Every existing token is hashed in place, so links that were already sent keep working,
+// and the plaintext column is dropped afterwards. It does nothing once the column is gone.
+func MigrateTokenHashes(db *gorm.DB) error {
+    if !db.Migrator().HasColumn(&Token{}, "token") {
+        return nil
+    }
+
+    return db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Exec("UPDATE tokens SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex') WHERE token_hash IS NULL").Error; err != nil {
+            return fmt.Errorf("failed to hash existing tokens: %w", err)
+        }
+        if err := tx.Migrator().DropColumn(&Token{}, "token"); err != nil {
+            return fmt.Errorf("failed to drop plaintext token column: %w", err)
+        }
+        return nil
+    })
+}
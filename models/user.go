@@ -2,6 +2,8 @@
 package models
 
 import (
+	"fmt"
+	"path"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -17,16 +19,39 @@ const (
 	RoleMember  Role = "member"
 )
 
-// User represents the user model
+// UserStatus represents whether an account may be used
+type UserStatus string
+
+const (
+	UserStatusActive   UserStatus = "active"
+	UserStatusDisabled UserStatus = "disabled" // Disabled by an admin; login and API access are refused
+	UserStatusDeleted  UserStatus = "deleted"  // Personal data removed after the deletion grace period; authored content is kept
+)
+
+// User represents the user model.
+// Account state (pending email, status, lockout, scheduled deletion) is left out of the JSON because users
+// are embedded in teams, projects and tasks shown to other users; see the userAccount response in controllers.
 type User struct {
 	ID                  uint            `gorm:"primaryKey" json:"id"`
 	CreatedAt           time.Time       `json:"created_at"`
 	UpdatedAt           time.Time       `json:"updated_at"`
 	Username            string          `gorm:"uniqueIndex;not null" json:"username" validate:"required"`
 	Email               string          `gorm:"uniqueIndex;not null" json:"email" validate:"required,email"`
+	PendingEmail        string          `gorm:"type:varchar(255)" json:"-"` // New address waiting for confirmation
+	AvatarKey           string          `gorm:"type:varchar(255)" json:"-"`                       // Storage prefix of the avatar thumbnails, empty without avatar
+	AvatarURL           string          `gorm:"-" json:"avatar_url,omitempty"`                    // Filled in by AfterFind
 	Password            string          `gorm:"not null" json:"-"`
 	Role                Role            `gorm:"type:varchar(50);not null" json:"role" validate:"required,oneof=admin manager member"`
 	IsEmailVerified     bool            `gorm:"default:false" json:"is_email_verified"`
+	Status              UserStatus      `gorm:"type:varchar(20);not null;default:active" json:"-"`
+	PasswordResetRequired bool          `gorm:"not null;default:false" json:"-"` // Set by an admin; cleared when the password is reset
+	DeletionScheduledAt *time.Time      `gorm:"index" json:"-"` // The account is anonymized at this time unless the user logs in again
+	FailedLoginAttempts int             `gorm:"not null;default:0" json:"-"`
+	LastFailedLoginAt   *time.Time      `json:"-"`
+	LockedUntil         *time.Time      `json:"-"`
+	TOTPSecret          string          `gorm:"type:varchar(64)" json:"-"`
+	TOTPEnabled         bool            `gorm:"not null;default:false" json:"two_factor_enabled"`
+	TOTPLastUsedStep    int64           `gorm:"not null;default:0" json:"-"`
 	PasswordResetTokens []Token         `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"password_reset_tokens,omitempty"`
 	EmailVerifyTokens   []Token         `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"email_verify_tokens,omitempty"`
 	Projects            []Project       `gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE" json:"projects,omitempty"`
@@ -36,6 +61,20 @@ type User struct {
 	Teams     []Team     `gorm:"many2many:team_members;constraint:OnDelete:CASCADE" json:"teams,omitempty"` // Optional
 }
 
+// AvatarPath returns the public path serving the user's avatar. The avatar key is part of the query,
+// so clients and caches pick up a replaced avatar right away.
+func AvatarPath(userID uint, avatarKey string) string {
+	return fmt.Sprintf("/users/%d/avatar?v=%s", userID, path.Base(avatarKey))
+}
+
+// AfterFind fills in AvatarURL, so every User in a response, embedded ones included, carries its avatar
+func (u *User) AfterFind(tx *gorm.DB) (err error) {
+	if u.AvatarKey != "" {
+		u.AvatarURL = AvatarPath(u.ID, u.AvatarKey)
+	}
+	return
+}
+
 // BeforeCreate hashes the password before saving to the database
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
@@ -58,6 +97,16 @@ func (u *User) BeforeUpdate(tx *gorm.DB) (err error) {
 	return
 }
 
+// IsLocked reports whether the account is temporarily locked after too many failed logins
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(now)
+}
+
+// IsDisabled reports whether the account has been disabled by an admin or deleted
+func (u *User) IsDisabled() bool {
+	return u.Status == UserStatusDisabled || u.Status == UserStatusDeleted
+}
+
 // ComparePassword compares a plain text password with the hashed password
 func (u *User) ComparePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
@@ -0,0 +1,109 @@
+// models/user_cache.go
+package models
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserCacheTTL bounds how long a cached user is used by AuthMiddleware. Writes to the users table made
+// by this process invalidate the cache right away; the TTL covers writes made by other instances.
+var UserCacheTTL = 30 * time.Second
+
+// userCacheColumns are the columns loaded into the cache: what AuthMiddleware needs to authorize a request
+// and what handlers put in responses. Credentials, TOTP state, the pending email, the deletion schedule and
+// lockout counters are left out, so handlers that check or change them load the user from the database.
+var userCacheColumns = []string{
+	"id", "created_at", "updated_at", "username", "email", "avatar_key",
+	"role", "is_email_verified", "status", "password_reset_required",
+}
+
+// cachedUser is a user loaded by GetCachedUser together with the time it stops being used
+type cachedUser struct {
+	user      User
+	expiresAt time.Time
+}
+
+var (
+	userCacheMu sync.RWMutex
+	userCache   = make(map[uint]cachedUser)
+	// userCacheGeneration changes on every invalidation, so a load that raced with an update is not stored
+	userCacheGeneration uint64
+)
+
+// GetCachedUser returns the user with the given ID, loading it from the database when it is not cached
+// or the cached copy has expired. Only userCacheColumns are filled in.
+// It returns gorm.ErrRecordNotFound when the user does not exist.
+func GetCachedUser(userID uint) (User, error) {
+	now := time.Now()
+
+	userCacheMu.RLock()
+	entry, ok := userCache[userID]
+	generation := userCacheGeneration
+	userCacheMu.RUnlock()
+	if ok && entry.expiresAt.After(now) {
+		return entry.user, nil
+	}
+
+	var user User
+	if err := DB.Select(userCacheColumns).First(&user, userID).Error; err != nil {
+		return User{}, err
+	}
+
+	userCacheMu.Lock()
+	defer userCacheMu.Unlock()
+	// The row may have been read before an update that invalidated the cache in the meantime
+	if generation != userCacheGeneration {
+		return user, nil
+	}
+	// Forget expired users so the map does not grow forever
+	if len(userCache) > 10000 {
+		for id, cached := range userCache {
+			if !cached.expiresAt.After(now) {
+				delete(userCache, id)
+			}
+		}
+	}
+	userCache[userID] = cachedUser{user: user, expiresAt: now.Add(UserCacheTTL)}
+
+	return user, nil
+}
+
+// InvalidateCachedUser removes a user from the cache, so the next request loads it again
+func InvalidateCachedUser(userID uint) {
+	userCacheMu.Lock()
+	delete(userCache, userID)
+	userCacheGeneration++
+	userCacheMu.Unlock()
+}
+
+// clearUserCache removes every user from the cache
+func clearUserCache() {
+	userCacheMu.Lock()
+	userCache = make(map[uint]cachedUser)
+	userCacheGeneration++
+	userCacheMu.Unlock()
+}
+
+// RegisterUserCacheCallbacks invalidates cached users whenever the users table is updated or rows are deleted
+// from it, so changes such as disabling an account or changing its role take effect on the next request.
+// Updates written as "WHERE id = ?" do not tell which users they touched, so they clear the whole cache.
+func RegisterUserCacheCallbacks(db *gorm.DB) error {
+	invalidate := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement.Schema == nil || tx.Statement.Schema.Table != "users" {
+			return
+		}
+		if user, ok := tx.Statement.Model.(*User); ok && user.ID != 0 {
+			InvalidateCachedUser(user.ID)
+			return
+		}
+		clearUserCache()
+	}
+
+	if err := db.Callback().Update().After("gorm:update").Register("models:invalidate_user_cache", invalidate); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register("models:invalidate_user_cache", invalidate)
+}
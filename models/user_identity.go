@@ -0,0 +1,34 @@
+// models/user_identity.go
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity links an account at an external OpenID Connect provider to a user.
+// A provider subject can only be linked to one user.
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	User        *User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Provider    string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_provider_subject" json:"subject"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OAuthState stores the state, nonce and PKCE verifier of an authorization request
+// between /start and /callback. When UserID is set the callback links the identity
+// to that user instead of logging in.
+type OAuthState struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	State        string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Provider     string    `gorm:"type:varchar(50);not null" json:"provider"`
+	Nonce        string    `gorm:"type:varchar(64);not null" json:"-"`
+	CodeVerifier string    `gorm:"type:varchar(128);not null" json:"-"`
+	UserID       *uint     `gorm:"index" json:"user_id,omitempty"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+}
@@ -0,0 +1,84 @@
+// models/user_preferences.go
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DateFormats maps the date formats a user can choose to the Go layout used to render them
+var DateFormats = map[string]string{
+	"YYYY-MM-DD":  "2006-01-02",
+	"DD/MM/YYYY":  "02/01/2006",
+	"MM/DD/YYYY":  "01/02/2006",
+	"DD MMM YYYY": "02 Jan 2006",
+}
+
+// Default preferences of users who never saved their own
+const (
+	DefaultTimezone   = "UTC"
+	DefaultLocale     = "en"
+	DefaultDateFormat = "DD MMM YYYY"
+)
+
+// UserPreferences holds the display and notification settings of a user.
+// Users without a row get DefaultUserPreferences.
+type UserPreferences struct {
+	UserID             uint      `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	Timezone           string    `gorm:"type:varchar(64);not null;default:UTC" json:"timezone"` // IANA name, e.g. Asia/Jakarta
+	Locale             string    `gorm:"type:varchar(35);not null;default:en" json:"locale"`    // BCP 47 language tag
+	DateFormat         string    `gorm:"type:varchar(20);not null" json:"date_format"`          // One of the keys of DateFormats
+	DefaultProjectID   *uint     `json:"default_project_id"`                                    // Project opened first by clients
+	DefaultProject     *Project  `gorm:"foreignKey:DefaultProjectID;constraint:OnDelete:SET NULL" json:"-"`
+	EmailNotifications bool      `gorm:"not null" json:"email_notifications"` // Security and account emails are always sent
+}
+
+// DefaultUserPreferences returns the preferences used for a user who has not saved any
+func DefaultUserPreferences(userID uint) UserPreferences {
+	return UserPreferences{
+		UserID:             userID,
+		Timezone:           DefaultTimezone,
+		Locale:             DefaultLocale,
+		DateFormat:         DefaultDateFormat,
+		EmailNotifications: true,
+	}
+}
+
+// GetUserPreferences returns the saved preferences of a user, or the defaults when there are none
+func GetUserPreferences(userID uint) (UserPreferences, error) {
+	var prefs UserPreferences
+	err := DB.Where("user_id = ?", userID).First(&prefs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultUserPreferences(userID), nil
+	}
+	return prefs, err
+}
+
+// Location returns the time zone of the user. An unknown zone falls back to UTC,
+// zones are validated when saved but the tz database of the server may change.
+func (p UserPreferences) Location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// FormatTime renders t in the user's time zone and date format, as used in emails
+func (p UserPreferences) FormatTime(t time.Time) string {
+	layout, ok := DateFormats[p.DateFormat]
+	if !ok {
+		layout = DateFormats[DefaultDateFormat]
+	}
+	return t.In(p.Location()).Format(layout + " 15:04 MST")
+}
+
+// StartOfDay returns midnight of the day t falls on in the user's time zone
+func (p UserPreferences) StartOfDay(t time.Time) time.Time {
+	local := t.In(p.Location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+}
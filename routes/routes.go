@@ -2,9 +2,13 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
 	"github.com/mfuadfakhruzzaki/backendaurauran/controllers"
 	"github.com/mfuadfakhruzzaki/backendaurauran/middlewares"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
 	"github.com/mfuadfakhruzzaki/backendaurauran/storage"
 	"gorm.io/gorm"
 )
@@ -16,6 +20,7 @@ func SetupRouter(db *gorm.DB, storageService storage.StorageService, bucketName
 	// Initialize controllers with dependencies
 	fileController := controllers.NewFileController(db, storageService, bucketName)
 	notificationController := controllers.NewNotificationController(db)
+	avatarController := controllers.NewAvatarController(db, storageService, bucketName)
 	// Inisialisasi controller lain jika diperlukan, misalnya:
 	// userController := controllers.NewUserController(db)
 	// teamController := controllers.NewTeamController(db)
@@ -23,18 +28,34 @@ func SetupRouter(db *gorm.DB, storageService storage.StorageService, bucketName
 	// ... dan seterusnya
 
 	// Global middleware
-	router.Use(middlewares.CORSMiddleware())
+	router.Use(middlewares.CORSMiddleware(config.AppConfig.Server.AllowedOrigins))
 	router.Use(middlewares.LoggingMiddleware())
 	router.Use(middlewares.RecoveryMiddleware())
 	router.Use(middlewares.RateLimitMiddleware())
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", controllers.JWKS)
+
+	// Avatars are public so they can be shown with plain <img> tags
+	router.GET("/users/:user_id/avatar", avatarController.GetAvatar)
+
 	// Auth routes
 	auth := router.Group("/auth")
 	{
 		auth.POST("/register", controllers.Register)
 		auth.POST("/login", controllers.Login)
+		auth.POST("/mfa/verify", controllers.VerifyMFA)
+		auth.POST("/magic-link", middlewares.RouteRateLimitMiddleware(5, 15*time.Minute), controllers.RequestMagicLink)
+		auth.POST("/magic-link/verify", controllers.VerifyMagicLink)
+		auth.POST("/refresh", controllers.RefreshToken)
 		auth.POST("/logout", controllers.Logout)
+		auth.GET("/csrf", controllers.CSRFToken)
 		auth.GET("/verify-email", controllers.VerifyEmail)
+		auth.POST("/resend-verification", middlewares.RouteRateLimitMiddleware(5, 15*time.Minute), controllers.ResendVerification)
+		auth.GET("/confirm-email-change", controllers.ConfirmEmailChange)
+		auth.GET("/unlock-account", controllers.UnlockAccount)
+		auth.GET("/oauth/:provider/start", controllers.OAuthStart)
+		auth.GET("/oauth/:provider/callback", controllers.OAuthCallback)
 		auth.POST("/request-password-reset", controllers.RequestPasswordReset)
 		auth.POST("/reset-password", controllers.ResetPassword)
 		auth.GET("/reset-password", controllers.ResetPasswordForm)
@@ -48,102 +69,201 @@ func SetupRouter(db *gorm.DB, storageService storage.StorageService, bucketName
 		// User routes
 		user := protected.Group("/users")
 		{
-			user.GET("/profile", controllers.GetProfile)
-			user.PUT("/profile", controllers.UpdateProfile)
-			user.DELETE("/profile", controllers.DeleteProfile)
+			user.GET("/profile", middlewares.ScopeMiddleware("profile"), controllers.GetProfile)
+			user.GET("/preferences", middlewares.ScopeMiddleware("profile"), controllers.GetPreferences)
+			user.GET("/search", middlewares.ScopeMiddleware("profile"), middlewares.UserRateLimitMiddleware(30, time.Minute), controllers.SearchUsers)
+
+			// Team invitations sent to the email address of the user
+			invitations := user.Group("/invitations")
+			invitations.Use(middlewares.ScopeMiddleware("teams"))
+			{
+				invitations.GET("", controllers.ListMyTeamInvitations)
+				invitations.POST("/accept", controllers.AcceptTeamInvitationByToken)
+				invitations.POST("/decline", controllers.DeclineTeamInvitationByToken)
+				invitations.POST("/:invitation_id/accept", controllers.AcceptTeamInvitation)
+				invitations.POST("/:invitation_id/decline", controllers.DeclineTeamInvitation)
+			}
+
+			// Account management is only available with a login session, not with personal access tokens
+			account := user.Group("")
+			account.Use(middlewares.SessionOnlyMiddleware())
+			{
+				account.PUT("/profile", controllers.UpdateProfile)
+				account.DELETE("/profile", controllers.DeleteProfile)
+				account.GET("/profile/export", controllers.ExportProfile)
+				account.PUT("/preferences", controllers.UpdatePreferences)
+				account.POST("/profile/avatar", avatarController.UploadAvatar)
+				account.PUT("/profile/avatar", avatarController.UploadAvatar)
+				account.DELETE("/profile/avatar", avatarController.DeleteAvatar)
+
+				// Two-factor authentication
+				account.POST("/2fa/enroll", controllers.EnrollTwoFactor)
+				account.POST("/2fa/confirm", controllers.ConfirmTwoFactor)
+				account.POST("/2fa/disable", controllers.DisableTwoFactor)
+				account.POST("/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+
+				// Sessions (logged-in devices)
+				account.GET("/sessions", controllers.ListSessions)
+				account.DELETE("/sessions", controllers.RevokeAllSessions)
+				account.DELETE("/sessions/:session_id", controllers.RevokeSession)
+
+				// Linked OAuth / OpenID Connect accounts
+				account.GET("/identities", controllers.ListIdentities)
+				account.POST("/identities/:provider/link", controllers.LinkIdentity)
+				account.DELETE("/identities/:identity_id", controllers.UnlinkIdentity)
+
+				// Personal access tokens
+				account.POST("/tokens", controllers.CreatePersonalAccessToken)
+				account.GET("/tokens", controllers.ListPersonalAccessTokens)
+				account.DELETE("/tokens/:token_id", controllers.RevokePersonalAccessToken)
+			}
+		}
+
+		// Admin routes (requires a login session and the admin permissions)
+		admin := protected.Group("/admin")
+		admin.Use(middlewares.SessionOnlyMiddleware())
+		{
+			// Invitation routes
+			invitations := admin.Group("/invitations")
+			invitations.Use(middlewares.RequirePermission(models.PermissionInvitationManage))
+			{
+				invitations.POST("/", controllers.CreateInvitation)
+				invitations.GET("/", controllers.ListInvitations)
+				invitations.DELETE("/:invitation_id", controllers.RevokeInvitation)
+			}
+
+			// User management routes
+			users := admin.Group("/users")
+			users.Use(middlewares.RequirePermission(models.PermissionUserManage))
+			{
+				users.GET("/", controllers.ListUsers)
+				users.GET("/:user_id", controllers.GetUser)
+				users.PUT("/:user_id/role", controllers.UpdateUserRole)
+				users.POST("/:user_id/disable", controllers.DisableUser)
+				users.POST("/:user_id/enable", controllers.EnableUser)
+				users.POST("/:user_id/logout", controllers.ForceLogoutUser)
+				users.POST("/:user_id/force-password-reset", controllers.ForcePasswordReset)
+				users.POST("/:user_id/unlock", controllers.UnlockUser)
+			}
 		}
 
 		// Team routes
 		team := protected.Group("/teams")
+		team.Use(middlewares.ScopeMiddleware("teams"))
 		{
-			team.POST("/", controllers.CreateTeam)
+			team.POST("/", middlewares.RequirePermission(models.PermissionTeamCreate), controllers.CreateTeam)
 			team.GET("/", controllers.ListTeams)
-			team.GET("/:team_id", controllers.GetTeam)
-			team.PUT("/:team_id", controllers.UpdateTeam)
-			team.DELETE("/:team_id", controllers.DeleteTeam)
+			team.GET("/:team_id", middlewares.RequirePermission(models.PermissionTeamView), controllers.GetTeam)
+			team.PUT("/:team_id", middlewares.RequirePermission(models.PermissionTeamUpdate), controllers.UpdateTeam)
+			team.DELETE("/:team_id", middlewares.RequirePermission(models.PermissionTeamDelete), controllers.DeleteTeam)
+			team.POST("/:team_id/transfer", middlewares.RequirePermission(models.PermissionTeamTransfer), controllers.TransferTeam)
+			team.PUT("/:team_id/parent", middlewares.RequirePermission(models.PermissionTeamUpdate), controllers.SetTeamParent)
 
 			// Team Members routes
 			members := team.Group("/:team_id/members")
 			{
-				members.POST("/", controllers.AddTeamMember)
-				members.GET("/", controllers.ListTeamMembers)
-				members.DELETE("/:user_id", controllers.RemoveTeamMember)
+				members.POST("/", middlewares.RequirePermission(models.PermissionTeamManageMembers), controllers.AddTeamMember)
+				members.GET("/", middlewares.RequirePermission(models.PermissionTeamView), controllers.ListTeamMembers)
+				members.DELETE("/:user_id", middlewares.RequirePermission(models.PermissionTeamManageMembers), controllers.RemoveTeamMember)
+				members.PUT("/:user_id/role", middlewares.RequirePermission(models.PermissionTeamManageRoles), controllers.UpdateTeamMemberRole)
+			}
+
+			// Team Invitations routes
+			teamInvitations := team.Group("/:team_id/invitations")
+			teamInvitations.Use(middlewares.RequirePermission(models.PermissionTeamManageMembers))
+			{
+				teamInvitations.POST("/", controllers.CreateTeamInvitation)
+				teamInvitations.GET("/", controllers.ListTeamInvitations)
+				teamInvitations.DELETE("/:invitation_id", controllers.RevokeTeamInvitation)
 			}
 		}
 
 		// Project routes
 		project := protected.Group("/projects")
 		{
-			project.POST("/", controllers.CreateProject)
-			project.GET("/", controllers.ListProjects)
-			project.GET("/:project_id", controllers.GetProject)
-			project.PUT("/:project_id", controllers.UpdateProject)
-			project.DELETE("/:project_id", controllers.DeleteProject)
+			// Project, collaborator and project team routes share the "projects" scope
+			projects := project.Group("")
+			projects.Use(middlewares.ScopeMiddleware("projects"))
+			{
+				projects.POST("/", middlewares.RequirePermission(models.PermissionProjectCreate), controllers.CreateProject)
+				projects.GET("/", controllers.ListProjects)
+				projects.GET("/:project_id", middlewares.RequirePermission(models.PermissionProjectView), controllers.GetProject)
+				projects.PUT("/:project_id", middlewares.RequirePermission(models.PermissionProjectUpdate), controllers.UpdateProject)
+				projects.DELETE("/:project_id", middlewares.RequirePermission(models.PermissionProjectDelete), controllers.DeleteProject)
+				projects.POST("/:project_id/transfer", middlewares.RequirePermission(models.PermissionProjectTransfer), controllers.TransferProject)
+			}
 
 			// Collaborators routes
 			collab := project.Group("/:project_id/collaborators")
+			collab.Use(middlewares.ScopeMiddleware("projects"))
 			{
-				collab.POST("/", controllers.AddCollaborator)
-				collab.GET("/", controllers.ListCollaborators)
-				collab.PUT("/:collaborator_id", controllers.UpdateCollaboratorRole)
-				collab.DELETE("/:collaborator_id", controllers.RemoveCollaborator)
+				collab.POST("/", middlewares.RequirePermission(models.PermissionProjectManageCollaborators), controllers.AddCollaborator)
+				collab.GET("/", middlewares.RequirePermission(models.PermissionProjectView), controllers.ListCollaborators)
+				collab.PUT("/:collaborator_id", middlewares.RequirePermission(models.PermissionProjectManageCollaborators), controllers.UpdateCollaboratorRole)
+				collab.DELETE("/:collaborator_id", middlewares.RequirePermission(models.PermissionProjectManageCollaborators), controllers.RemoveCollaborator)
 			}
 
 			// Project Teams routes
 			projectTeams := project.Group("/:project_id/teams")
+			projectTeams.Use(middlewares.ScopeMiddleware("projects"))
 			{
-				projectTeams.POST("/", controllers.AddProjectTeam)
-				projectTeams.GET("/", controllers.ListProjectTeams)
-				projectTeams.DELETE("/:team_id", controllers.RemoveProjectTeam)
+				projectTeams.POST("/", middlewares.RequirePermission(models.PermissionProjectManageTeams), controllers.AddProjectTeam)
+				projectTeams.GET("/", middlewares.RequirePermission(models.PermissionProjectView), controllers.ListProjectTeams)
+				projectTeams.DELETE("/:team_id", middlewares.RequirePermission(models.PermissionProjectManageTeams), controllers.RemoveProjectTeam)
 			}
 
 			// Activity routes
 			activity := project.Group("/:project_id/activities")
+			activity.Use(middlewares.ScopeMiddleware("activities"))
 			{
-				activity.POST("/", controllers.CreateActivity)
-				activity.GET("/", controllers.ListActivities)
-				activity.GET("/:activity_id", controllers.GetActivity)
-				activity.PUT("/:activity_id", controllers.UpdateActivity)
-				activity.DELETE("/:activity_id", controllers.DeleteActivity)
+				activity.POST("/", middlewares.RequirePermission(models.PermissionActivityCreate), controllers.CreateActivity)
+				activity.GET("/", middlewares.RequirePermission(models.PermissionActivityView), controllers.ListActivities)
+				activity.GET("/:activity_id", middlewares.RequirePermission(models.PermissionActivityView), controllers.GetActivity)
+				activity.PUT("/:activity_id", middlewares.RequirePermission(models.PermissionActivityUpdate), controllers.UpdateActivity)
+				activity.DELETE("/:activity_id", middlewares.RequirePermission(models.PermissionActivityDelete), controllers.DeleteActivity)
 			}
 
 			// Task routes
 			task := project.Group("/:project_id/tasks")
+			task.Use(middlewares.ScopeMiddleware("tasks"))
 			{
-				task.POST("/", controllers.CreateTask)
-				task.GET("/", controllers.ListTasks)
-				task.GET("/:task_id", controllers.GetTask)
-				task.PUT("/:task_id", controllers.UpdateTask)
-				task.DELETE("/:task_id", controllers.DeleteTask)
+				task.POST("/", middlewares.RequirePermission(models.PermissionTaskCreate), controllers.CreateTask)
+				task.GET("/", middlewares.RequirePermission(models.PermissionTaskView), controllers.ListTasks)
+				task.GET("/:task_id", middlewares.RequirePermission(models.PermissionTaskView), controllers.GetTask)
+				task.PUT("/:task_id", middlewares.RequirePermission(models.PermissionTaskUpdate), controllers.UpdateTask)
+				task.DELETE("/:task_id", middlewares.RequirePermission(models.PermissionTaskDelete), controllers.DeleteTask)
 			}
 
 			// Note routes
 			note := project.Group("/:project_id/notes")
+			note.Use(middlewares.ScopeMiddleware("notes"))
 			{
-				note.POST("/", controllers.CreateNote)
-				note.GET("/", controllers.ListNotes)
-				note.GET("/:id", controllers.GetNote)
-				note.PUT("/:id", controllers.UpdateNote)
-				note.DELETE("/:id", controllers.DeleteNote)
+				note.POST("/", middlewares.RequirePermission(models.PermissionNoteCreate), controllers.CreateNote)
+				note.GET("/", middlewares.RequirePermission(models.PermissionNoteView), controllers.ListNotes)
+				note.GET("/:id", middlewares.RequirePermission(models.PermissionNoteView), controllers.GetNote)
+				note.PUT("/:id", middlewares.RequirePermission(models.PermissionNoteUpdate), controllers.UpdateNote)
+				note.DELETE("/:id", middlewares.RequirePermission(models.PermissionNoteDelete), controllers.DeleteNote)
 			}
 
 			// File routes (using fileController instance methods)
 			file := project.Group("/:project_id/files")
+			file.Use(middlewares.ScopeMiddleware("files"))
 			{
-				file.POST("/", fileController.UploadFile)
-				file.GET("/", fileController.ListFiles)
-				file.GET("/:file_id", fileController.DownloadFile)
-				file.DELETE("/:file_id", fileController.DeleteFile)
+				file.POST("/", middlewares.RequirePermission(models.PermissionFileUpload), fileController.UploadFile)
+				file.GET("/", middlewares.RequirePermission(models.PermissionFileView), fileController.ListFiles)
+				file.GET("/:file_id", middlewares.RequirePermission(models.PermissionFileView), fileController.DownloadFile)
+				file.DELETE("/:file_id", middlewares.RequirePermission(models.PermissionFileDelete), fileController.DeleteFile)
 			}
 
 			// Notification routes (using notificationController instance methods)
 			notification := project.Group("/:project_id/notifications")
+			notification.Use(middlewares.ScopeMiddleware("notifications"))
 			{
-				notification.POST("/", notificationController.CreateNotification)
-				notification.GET("/", notificationController.ListNotifications)
-				notification.GET("/:notification_id", notificationController.GetNotification)
-				notification.PUT("/:notification_id", notificationController.UpdateNotification)
-				notification.DELETE("/:notification_id", notificationController.DeleteNotification)
+				notification.POST("/", middlewares.RequirePermission(models.PermissionNotificationCreate), notificationController.CreateNotification)
+				notification.GET("/", middlewares.RequirePermission(models.PermissionNotificationView), notificationController.ListNotifications)
+				notification.GET("/:notification_id", middlewares.RequirePermission(models.PermissionNotificationView), notificationController.GetNotification)
+				notification.PUT("/:notification_id", middlewares.RequirePermission(models.PermissionNotificationUpdate), notificationController.UpdateNotification)
+				notification.DELETE("/:notification_id", middlewares.RequirePermission(models.PermissionNotificationDelete), notificationController.DeleteNotification)
 			}
 		}
 	}
@@ -0,0 +1,116 @@
+// utils/avatar.go
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+
+	// Decoders for the accepted avatar formats
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/mfuadfakhruzzaki/backendaurauran/storage"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// AvatarSizes adalah ukuran thumbnail persegi (px) yang dibuat untuk setiap avatar
+var AvatarSizes = []int{64, 128, 256}
+
+// DefaultAvatarSize adalah ukuran yang dikirim jika request tidak menyebut ukuran
+const DefaultAvatarSize = 128
+
+// avatarMaxPixels membatasi resolusi gambar yang mau didecode, agar gambar kecil yang mengembang
+// menjadi sangat besar di memori (decompression bomb) ditolak
+const avatarMaxPixels = 40_000_000
+
+// avatarMimeTypes adalah jenis gambar yang diterima sebagai avatar
+var avatarMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var (
+	// ErrAvatarType dikembalikan jika isi file bukan gambar yang diterima
+	ErrAvatarType = errors.New("avatar must be a JPEG, PNG, GIF or WebP image")
+	// ErrAvatarTooLarge dikembalikan jika resolusi gambar melebihi avatarMaxPixels
+	ErrAvatarTooLarge = errors.New("avatar image resolution is too large")
+)
+
+// ValidAvatarSize melaporkan apakah size termasuk AvatarSizes
+func ValidAvatarSize(size int) bool {
+	for _, s := range AvatarSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// AvatarObjectName mengembalikan nama objek storage untuk thumbnail avatar dengan ukuran tertentu
+func AvatarObjectName(avatarKey string, size int) string {
+	return fmt.Sprintf("%s/%d.png", avatarKey, size)
+}
+
+// ResizeAvatar memeriksa jenis file dari isinya (bukan dari header Content-Type), lalu membuat
+// thumbnail PNG persegi untuk setiap ukuran di AvatarSizes. Gambar dipotong di tengah agar menjadi persegi.
+func ResizeAvatar(data []byte) (map[int][]byte, error) {
+	if !avatarMimeTypes[mimetype.Detect(data).String()] {
+		return nil, ErrAvatarType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarType
+	}
+	if cfg.Width*cfg.Height > avatarMaxPixels {
+		return nil, ErrAvatarTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarType
+	}
+	crop := squareCenter(src.Bounds())
+
+	thumbnails := make(map[int][]byte, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, fmt.Errorf("failed to encode avatar: %w", err)
+		}
+		thumbnails[size] = buf.Bytes()
+	}
+	return thumbnails, nil
+}
+
+// squareCenter mengembalikan persegi terbesar di tengah area gambar
+func squareCenter(bounds image.Rectangle) image.Rectangle {
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// DeleteAvatarObjects menghapus semua thumbnail avatar dari storage
+func DeleteAvatarObjects(ctx context.Context, storageService storage.StorageService, bucketName, avatarKey string) error {
+	for _, size := range AvatarSizes {
+		if err := storageService.DeleteFile(ctx, bucketName, AvatarObjectName(avatarKey, size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
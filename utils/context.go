@@ -2,5 +2,8 @@
 package utils
 
 const (
-	ContextUserKey = "user"
+	ContextUserKey        = "user"
+	ContextSessionKey     = "session_id"
+	ContextPermissionsKey = "permissions"
+	ContextPreferencesKey = "preferences"
 )
@@ -0,0 +1,156 @@
+// utils/cookie.go
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+)
+
+const (
+	// CSRFHeaderName adalah header yang membawa token CSRF pada request yang mengubah data
+	CSRFHeaderName = "X-CSRF-Token"
+	// CSRFFormField adalah field tersembunyi yang membawa token CSRF pada form HTML
+	CSRFFormField = "csrf_token"
+)
+
+// refreshCookiePath membatasi cookie refresh token agar hanya dikirim ke route /auth
+const refreshCookiePath = "/auth"
+
+const (
+	// oauthStateCookieName adalah cookie yang mengikat state OAuth ke browser yang memulai login
+	oauthStateCookieName = "aurauran_oauth_state"
+	// oauthStateCookiePath membatasi cookie state agar hanya dikirim ke route /auth/oauth
+	oauthStateCookiePath = "/auth/oauth"
+)
+
+// setCookie menulis cookie dengan domain, Secure dan SameSite dari konfigurasi
+func setCookie(c *gin.Context, name, value, path string, maxAge time.Duration, httpOnly bool) {
+	cfg := config.AppConfig.Cookie
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cfg.Domain,
+		Secure:   cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: cfg.SameSite,
+	}
+	if maxAge > 0 {
+		cookie.MaxAge = int(maxAge.Seconds())
+	} else {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+// SetAuthCookies menyimpan access token dan refresh token di cookie HttpOnly
+func SetAuthCookies(c *gin.Context, accessToken, refreshToken string) {
+	cfg := config.AppConfig.Cookie
+	setCookie(c, cfg.AccessTokenName, accessToken, "/", config.AppConfig.JWT.AccessTokenTTL, true)
+	setCookie(c, cfg.RefreshTokenName, refreshToken, refreshCookiePath, config.AppConfig.JWT.RefreshTokenTTL, true)
+}
+
+// ClearAuthCookies menghapus cookie access token, refresh token dan CSRF
+func ClearAuthCookies(c *gin.Context) {
+	cfg := config.AppConfig.Cookie
+	setCookie(c, cfg.AccessTokenName, "", "/", 0, true)
+	setCookie(c, cfg.RefreshTokenName, "", refreshCookiePath, 0, true)
+	setCookie(c, cfg.CSRFName, "", "/", 0, false)
+}
+
+// AccessTokenCookie mengembalikan access token dari cookie, kosong jika mode cookie tidak aktif
+func AccessTokenCookie(c *gin.Context) string {
+	return authCookie(c, config.AppConfig.Cookie.AccessTokenName)
+}
+
+// RefreshTokenCookie mengembalikan refresh token dari cookie, kosong jika mode cookie tidak aktif
+func RefreshTokenCookie(c *gin.Context) string {
+	return authCookie(c, config.AppConfig.Cookie.RefreshTokenName)
+}
+
+// authCookie membaca cookie autentikasi jika mode cookie aktif
+func authCookie(c *gin.Context, name string) string {
+	if !config.AppConfig.Cookie.Enabled {
+		return ""
+	}
+	value, err := c.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// SetOAuthStateCookie menyimpan state OAuth di cookie HttpOnly, terlepas dari mode cookie autentikasi.
+// Callback dari provider adalah navigasi lintas situs, sehingga SameSite "strict" diturunkan menjadi "lax".
+func SetOAuthStateCookie(c *gin.Context, state string, maxAge time.Duration) {
+	cfg := config.AppConfig.Cookie
+	sameSite := cfg.SameSite
+	if sameSite == http.SameSiteStrictMode {
+		sameSite = http.SameSiteLaxMode
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookieName,
+		Value:    state,
+		Path:     oauthStateCookiePath,
+		Domain:   cfg.Domain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   cfg.Secure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+}
+
+// ClearOAuthStateCookie menghapus cookie state OAuth
+func ClearOAuthStateCookie(c *gin.Context) {
+	setCookie(c, oauthStateCookieName, "", oauthStateCookiePath, 0, true)
+}
+
+// ValidOAuthStateCookie memeriksa apakah state dari provider sama dengan state di cookie browser ini
+func ValidOAuthStateCookie(c *gin.Context, state string) bool {
+	expected, err := c.Cookie(oauthStateCookieName)
+	if err != nil || expected == "" || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(state)) == 1
+}
+
+// IssueCSRFToken membuat token CSRF baru dan menyimpannya di cookie yang dapat dibaca JavaScript.
+// Digunakan saat login agar setiap sesi memiliki token sendiri.
+func IssueCSRFToken(c *gin.Context) (string, error) {
+	token, err := GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	setCookie(c, config.AppConfig.Cookie.CSRFName, token, "/", config.AppConfig.JWT.RefreshTokenTTL, false)
+	return token, nil
+}
+
+// EnsureCSRFToken mengembalikan token CSRF dari cookie, atau membuat token baru jika belum ada
+func EnsureCSRFToken(c *gin.Context) (string, error) {
+	if token, err := c.Cookie(config.AppConfig.Cookie.CSRFName); err == nil && token != "" {
+		return token, nil
+	}
+	return IssueCSRFToken(c)
+}
+
+// ValidCSRFToken memeriksa token CSRF yang dikirim lewat header atau form terhadap cookie CSRF (double-submit)
+func ValidCSRFToken(c *gin.Context, submitted string) bool {
+	expected, err := c.Cookie(config.AppConfig.Cookie.CSRFName)
+	if err != nil || expected == "" || submitted == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(submitted)) == 1
+}
+
+// IsSafeMethod melaporkan apakah method HTTP tidak mengubah data, sehingga tidak perlu token CSRF
+func IsSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -3,10 +3,13 @@ package utils
 
 import (
 	"fmt"
+	"html"
 	"net/smtp"
 	"strconv"
+	"time"
 
 	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
 )
 
 // EmailService mengatur pengiriman email
@@ -70,3 +73,71 @@ func (e *EmailService) SendResetPasswordEmail(to string, token string) error {
              <p>Jika Anda tidak melakukan permintaan ini, silakan abaikan email ini.</p>`
 	return e.SendEmail(to, subject, body)
 }
+
+// SendUnlockAccountEmail mengirim email pemberitahuan akun terkunci beserta link untuk membukanya.
+// Waktu ditulis dalam zona waktu dan format tanggal pilihan user.
+func (e *EmailService) SendUnlockAccountEmail(to string, token string, lockedUntil time.Time, prefs models.UserPreferences) error {
+	unlockURL := fmt.Sprintf(config.AppConfig.Email.UnlockAccountURL, token)
+	subject := "Akun Anda Dikunci Sementara"
+	body := `<p>Halo,</p>
+             <p>Kami mendeteksi terlalu banyak percobaan login yang gagal pada akun Anda, sehingga akun dikunci sampai ` + prefs.FormatTime(lockedUntil) + `.</p>
+             <p>Jika itu memang Anda, klik link di bawah ini untuk membuka kunci akun sekarang:</p>
+             <a href="` + unlockURL + `">Buka Kunci Akun</a>
+             <p>Jika bukan Anda yang mencoba login, sebaiknya segera reset password Anda.</p>`
+	return e.SendEmail(to, subject, body)
+}
+
+// SendEmailChangeConfirmation mengirim link konfirmasi ke alamat email baru
+func (e *EmailService) SendEmailChangeConfirmation(to string, token string) error {
+	confirmURL := fmt.Sprintf(config.AppConfig.Email.EmailChangeURL, token)
+	subject := "Konfirmasi Perubahan Email"
+	body := `<p>Halo,</p>
+             <p>Anda meminta untuk mengganti alamat email akun Anda menjadi alamat ini. Silakan klik link di bawah ini untuk mengonfirmasi:</p>
+             <a href="` + confirmURL + `">Konfirmasi Email Baru</a>
+             <p>Jika Anda tidak melakukan permintaan ini, silakan abaikan email ini.</p>`
+	return e.SendEmail(to, subject, body)
+}
+
+// SendEmailChangeNotice memberi tahu alamat email lama bahwa ada permintaan perubahan email
+func (e *EmailService) SendEmailChangeNotice(to string, newEmail string) error {
+	subject := "Permintaan Perubahan Email"
+	body := `<p>Halo,</p>
+             <p>Kami menerima permintaan untuk mengganti alamat email akun Anda menjadi <strong>` + html.EscapeString(newEmail) + `</strong>. Perubahan baru berlaku setelah dikonfirmasi dari alamat baru tersebut.</p>
+             <p>Jika bukan Anda yang melakukan permintaan ini, segera ganti password Anda dan keluarkan semua sesi dari pengaturan akun.</p>`
+	return e.SendEmail(to, subject, body)
+}
+
+// SendMagicLinkEmail mengirim link login sekali pakai
+func (e *EmailService) SendMagicLinkEmail(to string, token string, expiresIn time.Duration) error {
+	loginURL := fmt.Sprintf(config.AppConfig.Email.MagicLinkURL, token)
+	subject := "Link Login Anda"
+	body := `<p>Halo,</p>
+             <p>Klik link di bawah ini untuk masuk ke akun Anda. Link hanya dapat dipakai sekali dan berlaku selama ` + strconv.Itoa(int(expiresIn.Minutes())) + ` menit.</p>
+             <a href="` + loginURL + `">Masuk</a>
+             <p>Jika Anda tidak meminta link ini, silakan abaikan email ini.</p>`
+	return e.SendEmail(to, subject, body)
+}
+
+// SendAccountDeletionScheduledEmail memberi tahu bahwa akun akan dihapus setelah masa tenggang.
+// Waktu ditulis dalam zona waktu dan format tanggal pilihan user.
+func (e *EmailService) SendAccountDeletionScheduledEmail(to string, deleteAt time.Time, prefs models.UserPreferences) error {
+	subject := "Akun Anda Dijadwalkan untuk Dihapus"
+	body := `<p>Halo,</p>
+             <p>Akun Anda dijadwalkan untuk dihapus pada ` + prefs.FormatTime(deleteAt) + `. Semua sesi Anda telah dikeluarkan.</p>
+             <p>Jika Anda berubah pikiran, cukup login kembali sebelum tanggal tersebut untuk membatalkan penghapusan.</p>
+             <p>Jika bukan Anda yang meminta penghapusan ini, segera login dan ganti password Anda.</p>`
+	return e.SendEmail(to, subject, body)
+}
+
+// SendTeamInvitationEmail mengirim undangan bergabung ke tim beserta link untuk menerimanya.
+// Waktu kedaluwarsa ditulis dalam zona waktu dan format tanggal pilihan user.
+func (e *EmailService) SendTeamInvitationEmail(to string, token string, teamName string, inviterName string, expiresAt time.Time, prefs models.UserPreferences) error {
+	acceptURL := fmt.Sprintf(config.AppConfig.Email.TeamInvitationURL, token)
+	subject := "Undangan Bergabung ke Tim"
+	body := `<p>Halo,</p>
+             <p><strong>` + html.EscapeString(inviterName) + `</strong> mengundang Anda untuk bergabung ke tim <strong>` + html.EscapeString(teamName) + `</strong>.</p>
+             <p>Klik link di bawah ini untuk menerima atau menolak undangan. Jika belum memiliki akun, daftar terlebih dahulu dengan alamat email ini.</p>
+             <a href="` + acceptURL + `">Lihat Undangan</a>
+             <p>Undangan berlaku sampai ` + prefs.FormatTime(expiresAt) + `. Jika Anda tidak mengenal pengirimnya, abaikan email ini.</p>`
+	return e.SendEmail(to, subject, body)
+}
@@ -2,62 +2,373 @@
 package utils
 
 import (
+	"crypto"
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/mfuadfakhruzzaki/backendaurauran/config"
 	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+	"gorm.io/gorm"
 )
 
+// jwtIssuer is the iss claim of every access token
+const jwtIssuer = "backendaurauran"
+
+// keyReloadInterval limits how often an unknown kid triggers reloading keys from the database
+const keyReloadInterval = 10 * time.Second
+
+// keyRefreshInterval is the longest keys are used for signing or served in the JWKS without reloading
+// them, so every instance picks up a rotation done by another one before the new key becomes active
+const keyRefreshInterval = time.Minute
+
+// JWKSMaxAge is how long clients may cache the JWKS
+const JWKSMaxAge = 5 * time.Minute
+
+// keyActivationDelay is how long a new key is published before it signs anything, so services holding
+// a key set cached from any instance can already verify the first tokens it signs
+const keyActivationDelay = JWKSMaxAge + keyRefreshInterval
+
 // Claims represents the JWT claims
 type Claims struct {
-    UserID uint   `json:"user_id"`
-    Role   string `json:"role"`
-    jwt.StandardClaims
+	UserID               uint   `json:"user_id"`
+	Role                 string `json:"role"`
+	jwt.RegisteredClaims        // ID (jti) is the ID of the session the token belongs to
+}
+
+// TokenService signs and verifies access tokens with the asymmetric keys stored in the
+// signing_keys table. Several keys can be valid at the same time, identified by kid.
+type TokenService struct {
+	mu       sync.RWMutex
+	current  *loadedSigningKey
+	keys     map[string]*loadedSigningKey
+	loadedAt time.Time
+	refresh  sync.Mutex // Serializes reloads of stale keys
 }
 
-// GenerateJWT generates a JWT token for a user
-func GenerateJWT(userID uint, role models.Role) (string, error) {
-    expirationTime := time.Now().Add(time.Hour * 24) // 24 jam
-    claims := &Claims{
-        UserID: userID,
-        Role:   string(role),
-        StandardClaims: jwt.StandardClaims{
-            ExpiresAt: expirationTime.Unix(),
-            IssuedAt:  time.Now().Unix(),
-            Issuer:    "backendaurauran",
-        },
-    }
+// loadedSigningKey is a decrypted SigningKey ready for use
+type loadedSigningKey struct {
+	kid       string
+	algorithm string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	public    crypto.PublicKey
+	createdAt time.Time
+	retiredAt *time.Time
+}
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    tokenString, err := token.SignedString([]byte(config.AppConfig.JWT.Secret))
-    if err != nil {
-        return "", err
-    }
+// Tokens is the token service used by the application
+var Tokens = &TokenService{keys: make(map[string]*loadedSigningKey)}
 
-    return tokenString, nil
+// InitTokenService loads the signing keys and creates the first key if none exists yet
+func InitTokenService() error {
+	if err := Tokens.Reload(); err != nil {
+		return err
+	}
+
+	Tokens.mu.RLock()
+	hasKey := Tokens.current != nil
+	Tokens.mu.RUnlock()
+
+	if !hasKey {
+		return Tokens.Rotate()
+	}
+	return nil
+}
+
+// GenerateJWT generates a short-lived access token for a user.
+// sessionID is stored as the jti so the token can be revoked together with its session.
+func GenerateJWT(userID uint, role models.Role, sessionID string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID: userID,
+		Role:   string(role),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(config.AppConfig.JWT.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    jwtIssuer,
+		},
+	}
+
+	return Tokens.Sign(claims)
 }
 
 // ParseJWT parses and validates a JWT token string
 func ParseJWT(tokenStr string) (*Claims, error) {
-    claims := &Claims{}
+	return Tokens.Parse(tokenStr)
+}
+
+// Sign signs the claims with the current signing key. A retired key never signs.
+func (s *TokenService) Sign(claims jwt.Claims) (string, error) {
+	now := time.Now()
+	s.reloadIfStale(now)
+
+	s.mu.RLock()
+	key := s.current
+	s.mu.RUnlock()
+
+	if key == nil || key.retired(now) {
+		return "", errors.New("no signing key available")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// reloadIfStale reloads the keys when they are older than keyRefreshInterval or the current key
+// has been retired. A failed reload is only logged: a key that is not retired yet keeps signing,
+// and its expiry allows for a missed refresh.
+func (s *TokenService) reloadIfStale(now time.Time) {
+	if !s.stale(now) {
+		return
+	}
+
+	// A single caller reloads while the others wait for it
+	s.refresh.Lock()
+	defer s.refresh.Unlock()
+	if !s.stale(now) {
+		return
+	}
+	if err := s.Reload(); err != nil {
+		Logger.Errorf("Failed to reload signing keys: %v", err)
+	}
+}
+
+// stale reports whether the loaded keys have to be reloaded before use
+func (s *TokenService) stale(now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current == nil || s.current.retired(now) || now.Sub(s.loadedAt) >= keyRefreshInterval
+}
+
+// Parse validates the signature, expiry and issuer of an access token and returns its claims
+func (s *TokenService) Parse(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{SigningAlgorithmRS256, SigningAlgorithmEdDSA}))
+
+	token, err := parser.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := s.verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.public, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if !claims.VerifyIssuer(jwtIssuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+
+	return claims, nil
+}
 
-    token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-        // Pastikan metode signing sesuai
-        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-            return nil, errors.New("unexpected signing method")
-        }
-        return []byte(config.AppConfig.JWT.Secret), nil
-    })
+// verificationKey looks up a key by kid, reloading from the database when the kid is
+// unknown, since another instance may have rotated keys
+func (s *TokenService) verificationKey(kid string) (*loadedSigningKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	loadedAt := s.loadedAt
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
 
-    if err != nil {
-        return nil, err
-    }
+	if time.Since(loadedAt) < keyReloadInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	key, ok = s.keys[kid]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// Reload loads all keys that are still valid for verification from the database.
+// The newest key that is active and not retired becomes the signing key.
+func (s *TokenService) Reload() error {
+	now := time.Now()
+
+	var rows []models.SigningKey
+	if err := models.DB.Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at desc").
+		Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	keys := make(map[string]*loadedSigningKey, len(rows))
+	var current *loadedSigningKey
+	for _, row := range rows {
+		key, err := loadSigningKey(row)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key %s: %w", row.Kid, err)
+		}
+		keys[key.kid] = key
+		active := row.ActivatesAt == nil || !row.ActivatesAt.After(now)
+		if current == nil && active && !key.retired(now) {
+			current = key
+		}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.current = current
+	s.loadedAt = now
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Rotate creates a new signing key with the configured algorithm and retires the previous ones.
+// The new key is published for keyActivationDelay before it becomes active and the previous keys are retired
+// at that moment, unless there is no key to sign with in the meantime. Retired keys keep verifying
+// tokens until the longest-lived token an instance may still sign with them has expired.
+func (s *TokenService) Rotate() error {
+	jwtConfig := config.AppConfig.JWT
+
+	private, err := generateSigningKey(jwtConfig.Algorithm)
+	if err != nil {
+		return err
+	}
+	publicPEM, err := encodePublicKey(private.Public())
+	if err != nil {
+		return err
+	}
+	privateEncrypted, err := encryptPrivateKey(private, jwtConfig.Secret)
+	if err != nil {
+		return err
+	}
+	kid, err := GenerateRandomToken(8)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	row := models.SigningKey{
+		Kid:        kid,
+		Algorithm:  jwtConfig.Algorithm,
+		PrivateKey: privateEncrypted,
+		PublicKey:  publicPEM,
+	}
+
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		var signing int64
+		if err := tx.Model(&models.SigningKey{}).
+			Where("(activates_at IS NULL OR activates_at <= ?) AND (retired_at IS NULL OR retired_at > ?)", now, now).
+			Count(&signing).Error; err != nil {
+			return err
+		}
+
+		activatesAt := now
+		if signing > 0 {
+			activatesAt = now.Add(keyActivationDelay)
+		}
+		row.ActivatesAt = &activatesAt
+		// An instance that missed the rotation signs with the old key until its next refresh
+		expiresAt := activatesAt.Add(keyRefreshInterval + jwtConfig.AccessTokenTTL + time.Minute)
+
+		if err := tx.Model(&models.SigningKey{}).
+			Where("retired_at IS NULL OR retired_at > ?", activatesAt).
+			Updates(map[string]interface{}{"retired_at": activatesAt, "expires_at": expiresAt}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&row).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store signing key: %w", err)
+	}
+
+	Logger.Infof("Signing key rotated: new kid %s (%s), signing from %s", kid, jwtConfig.Algorithm, row.ActivatesAt.Format(time.RFC3339))
+
+	return s.Reload()
+}
+
+// RotateIfDue rotates the signing key when the newest key is older than the configured rotation interval.
+// Keys are reloaded first so a rotation done by another instance, even one not active yet, is not repeated.
+func (s *TokenService) RotateIfDue() error {
+	if err := s.Reload(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.mu.RLock()
+	var newest *loadedSigningKey
+	for _, key := range s.keys {
+		if !key.retired(now) && (newest == nil || key.createdAt.After(newest.createdAt)) {
+			newest = key
+		}
+	}
+	s.mu.RUnlock()
+
+	if newest != nil && now.Sub(newest.createdAt) < config.AppConfig.JWT.KeyRotationInterval {
+		return nil
+	}
+	return s.Rotate()
+}
+
+// JWKS returns the public keys that verify access tokens as a JSON Web Key Set,
+// including keys that will sign tokens soon
+func (s *TokenService) JWKS() (map[string]interface{}, error) {
+	s.reloadIfStale(time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]map[string]string, 0, len(s.keys))
+	for _, key := range s.keys {
+		jwk, err := publicJWK(key.kid, key.algorithm, key.public)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwk)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i]["kid"] < keys[j]["kid"] })
+
+	return map[string]interface{}{"keys": keys}, nil
+}
+
+// retired reports whether the key may no longer sign tokens
+func (k *loadedSigningKey) retired(now time.Time) bool {
+	return k.retiredAt != nil && !k.retiredAt.After(now)
+}
 
-    if !token.Valid {
-        return nil, errors.New("invalid token")
-    }
+// loadSigningKey decrypts a stored key
+func loadSigningKey(row models.SigningKey) (*loadedSigningKey, error) {
+	method, err := signingMethod(row.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	private, err := decryptPrivateKey(row.PrivateKey, config.AppConfig.JWT.Secret)
+	if err != nil {
+		return nil, err
+	}
 
-    return claims, nil
+	return &loadedSigningKey{
+		kid:       row.Kid,
+		algorithm: row.Algorithm,
+		method:    method,
+		private:   private,
+		public:    private.Public(),
+		createdAt: row.CreatedAt,
+		retiredAt: row.RetiredAt,
+	}, nil
 }
@@ -0,0 +1,313 @@
+// utils/oidc.go
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"golang.org/x/oauth2"
+)
+
+// ErrUnknownOIDCProvider dikembalikan jika provider tidak dikonfigurasi di OAUTH_PROVIDERS
+var ErrUnknownOIDCProvider = errors.New("unknown OAuth provider")
+
+// oidcHTTPClient dipakai untuk discovery, JWKS dan penukaran kode
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OIDCClaims berisi claim ID token yang dipakai aplikasi
+type OIDCClaims struct {
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	Name              string       `json:"name"`
+	PreferredUsername string       `json:"preferred_username"`
+	Nonce             string       `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// flexibleBool menerima true/false maupun "true"/"false", karena sebagian provider mengirim email_verified sebagai string
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	*b = flexibleBool(s == "true")
+	return nil
+}
+
+// oidcDiscovery adalah bagian dokumen /.well-known/openid-configuration yang dibutuhkan
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider membungkus konfigurasi oauth2 dan kunci penandatangan sebuah provider OpenID Connect
+type OIDCProvider struct {
+	Name   string
+	Issuer string
+	oauth  *oauth2.Config
+	jwks   string
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+var (
+	oidcProvidersMu sync.Mutex
+	oidcProviders   = make(map[string]*OIDCProvider)
+)
+
+// GetOIDCProvider mengembalikan provider berdasarkan nama. Discovery dilakukan saat pertama kali
+// provider dipakai dan hasilnya disimpan, sehingga server tetap bisa start walaupun provider sedang tidak tersedia.
+func GetOIDCProvider(ctx context.Context, name string) (*OIDCProvider, error) {
+	cfg, ok := config.AppConfig.OAuth.Providers[name]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+
+	if provider, ok := oidcProviders[name]; ok {
+		return provider, nil
+	}
+
+	provider, err := NewOIDCProvider(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	oidcProviders[name] = provider
+	return provider, nil
+}
+
+// NewOIDCProvider melakukan discovery terhadap issuer dan menyiapkan konfigurasi oauth2
+func NewOIDCProvider(ctx context.Context, cfg config.OAuthProviderConfig) (*OIDCProvider, error) {
+	var doc oidcDiscovery
+	if err := oidcGetJSON(ctx, cfg.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed for %s: %w", cfg.Name, err)
+	}
+	if strings.TrimRight(doc.Issuer, "/") != cfg.Issuer {
+		return nil, fmt.Errorf("OIDC discovery for %s returned issuer %q, expected %q", cfg.Name, doc.Issuer, cfg.Issuer)
+	}
+
+	return &OIDCProvider{
+		Name:   cfg.Name,
+		Issuer: doc.Issuer,
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  doc.AuthorizationEndpoint,
+				TokenURL: doc.TokenEndpoint,
+			},
+		},
+		jwks: doc.JWKSURI,
+	}, nil
+}
+
+// AuthCodeURL membuat URL otorisasi dengan state, nonce dan PKCE (S256)
+func (p *OIDCProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.S256ChallengeOption(verifier),
+	)
+}
+
+// Exchange menukar authorization code dengan token, lalu memverifikasi ID token yang diterima
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*OIDCClaims, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, oidcHTTPClient)
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response does not contain an id_token")
+	}
+
+	return p.VerifyIDToken(ctx, rawIDToken, nonce)
+}
+
+// VerifyIDToken memeriksa tanda tangan, issuer, audience, masa berlaku dan nonce dari ID token
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OIDCClaims, error) {
+	claims := &OIDCClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if claims.Issuer != p.Issuer {
+		return nil, errors.New("invalid id_token: issuer mismatch")
+	}
+	if !claims.VerifyAudience(p.oauth.ClientID, true) {
+		return nil, errors.New("invalid id_token: audience mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id_token: missing subject")
+	}
+	if nonce != "" && claims.Nonce != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// publicKey mencari kunci berdasarkan kid. JWKS diambil ulang jika kid belum dikenal,
+// misalnya setelah provider merotasi kuncinya, dengan jeda minimal satu menit antar pengambilan.
+func (p *OIDCProvider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.RLock()
+	key, ok := p.lookupKey(kid)
+	fetchedAt := p.fetchedAt
+	p.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(fetchedAt) < time.Minute {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := fetchJWKS(ctx, p.jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	key, ok = p.lookupKey(kid)
+	p.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookupKey mencari kunci dengan kid tertentu; jika token tidak memiliki kid dan JWKS hanya berisi satu kunci, kunci itu dipakai
+func (p *OIDCProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey adalah satu entri JWKS (RFC 7517) untuk kunci RSA atau EC
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchJWKS mengambil dan mengurai kunci publik dari jwks_uri
+func fetchJWKS(ctx context.Context, uri string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := oidcGetJSON(ctx, uri, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			Logger.Warnf("Skipping JWKS key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey mengubah JWK menjadi kunci publik RSA atau ECDSA
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// oidcGetJSON melakukan GET dan mengurai response JSON ke out
+func oidcGetJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,231 @@
+// utils/oidc_test.go
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/sirupsen/logrus"
+)
+
+const stubClientID = "aurauran-test"
+
+// stubIssuer is a minimal OpenID Connect provider serving discovery, JWKS and token endpoints
+type stubIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]jwt.MapClaims
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	issuer := &stubIssuer{key: key, codes: make(map[string]jwt.MapClaims)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		issuer.mu.Lock()
+		claims, ok := issuer.codes[r.PostForm.Get("code")]
+		delete(issuer.codes, r.PostForm.Get("code"))
+		issuer.mu.Unlock()
+		if !ok || r.PostForm.Get("code_verifier") == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "stub-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     issuer.sign(t, claims, issuer.key),
+		})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+
+	return issuer
+}
+
+// claims returns valid ID token claims for the subject
+func (s *stubIssuer) claims(subject, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            stubClientID,
+		"sub":            subject,
+		"email":          subject + "@example.com",
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+}
+
+// sign creates an ID token signed with key under the kid published in the JWKS
+func (s *stubIssuer) sign(t *testing.T, claims jwt.MapClaims, key *rsa.PrivateKey) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "stub"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign id_token: %v", err)
+	}
+	return signed
+}
+
+// provider runs discovery against the stub issuer
+func (s *stubIssuer) provider(t *testing.T) *OIDCProvider {
+	t.Helper()
+	provider, err := NewOIDCProvider(context.Background(), config.OAuthProviderConfig{
+		Name:        "stub",
+		Issuer:      s.URL,
+		ClientID:    stubClientID,
+		RedirectURL: "http://localhost/auth/oauth/stub/callback",
+		Scopes:      []string{"openid", "email"},
+	})
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	return provider
+}
+
+func init() {
+	Logger = logrus.New()
+	Logger.SetOutput(io.Discard)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newStubIssuer(t)
+	provider := issuer.provider(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			token: func() string { return issuer.sign(t, issuer.claims("alice", "n-1"), issuer.key) },
+		},
+		{
+			name:    "bad signature",
+			token:   func() string { return issuer.sign(t, issuer.claims("alice", "n-1"), otherKey) },
+			wantErr: "verification error",
+		},
+		{
+			name: "issuer mismatch",
+			token: func() string {
+				claims := issuer.claims("alice", "n-1")
+				claims["iss"] = "https://evil.example.com"
+				return issuer.sign(t, claims, issuer.key)
+			},
+			wantErr: "issuer mismatch",
+		},
+		{
+			name: "audience mismatch",
+			token: func() string {
+				claims := issuer.claims("alice", "n-1")
+				claims["aud"] = "another-client"
+				return issuer.sign(t, claims, issuer.key)
+			},
+			wantErr: "audience mismatch",
+		},
+		{
+			name:    "nonce mismatch",
+			token:   func() string { return issuer.sign(t, issuer.claims("alice", "n-2"), issuer.key) },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := issuer.claims("alice", "n-1")
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+				return issuer.sign(t, claims, issuer.key)
+			},
+			wantErr: "expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.VerifyIDToken(context.Background(), tt.token(), "n-1")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if claims.Subject != "alice" || claims.Email != "alice@example.com" || !bool(claims.EmailVerified) {
+					t.Fatalf("unexpected claims: %+v", claims)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExchange(t *testing.T) {
+	issuer := newStubIssuer(t)
+	provider := issuer.provider(t)
+
+	issuer.codes["good-code"] = issuer.claims("bob", "n-1")
+
+	claims, err := provider.Exchange(context.Background(), "good-code", "verifier", "n-1")
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	if claims.Subject != "bob" {
+		t.Fatalf("expected subject bob, got %q", claims.Subject)
+	}
+
+	if _, err := provider.Exchange(context.Background(), "good-code", "verifier", "n-1"); err == nil {
+		t.Fatal("expected a reused code to be rejected")
+	}
+}
@@ -0,0 +1,157 @@
+// utils/password_policy.go
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"github.com/mfuadfakhruzzaki/backendaurauran/models"
+)
+
+// PasswordViolation describes one rule of the password policy that a password breaks
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var (
+	breachedPasswords   map[[sha1.Size]byte]struct{}
+	breachedPasswordsMu sync.RWMutex
+)
+
+// LoadBreachedPasswords reads the SHA-1 hashes of breached passwords from the configured list.
+// Each line holds one hex hash, optionally followed by ":count". Without a configured path the check is disabled.
+func LoadBreachedPasswords() error {
+	path := config.AppConfig.Password.BreachedListPath
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	hashes := make(map[[sha1.Size]byte]struct{})
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		var digest [sha1.Size]byte
+		if n, err := hex.Decode(digest[:], []byte(entry)); err != nil || n != sha1.Size {
+			return fmt.Errorf("invalid SHA-1 hash on line %d of breached password list", line)
+		}
+		hashes[digest] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	breachedPasswordsMu.Lock()
+	breachedPasswords = hashes
+	breachedPasswordsMu.Unlock()
+
+	Logger.Infof("Loaded %d breached password hashes", len(hashes))
+	return nil
+}
+
+// isBreachedPassword reports whether the password appears in the breached password list
+func isBreachedPassword(password string) bool {
+	breachedPasswordsMu.RLock()
+	defer breachedPasswordsMu.RUnlock()
+
+	if len(breachedPasswords) == 0 {
+		return false
+	}
+	_, found := breachedPasswords[sha1.Sum([]byte(password))]
+	return found
+}
+
+// ValidatePassword checks a new password against the password policy. username and email are the values
+// the account will have; userID is 0 for new accounts, otherwise the password history of that user is checked too.
+// It returns all violated rules, or an empty slice when the password is acceptable.
+func ValidatePassword(password, username, email string, userID uint) ([]PasswordViolation, error) {
+	policy := config.AppConfig.Password
+	violations := []PasswordViolation{}
+
+	if len([]rune(password)) < policy.MinLength {
+		violations = append(violations, PasswordViolation{"too_short", fmt.Sprintf("Password must be at least %d characters long", policy.MinLength)})
+	}
+	// bcrypt ignores everything after 72 bytes, so the maximum is counted in bytes
+	if len(password) > policy.MaxLength {
+		violations = append(violations, PasswordViolation{"too_long", fmt.Sprintf("Password must be at most %d bytes long", policy.MaxLength)})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		violations = append(violations, PasswordViolation{"missing_uppercase", "Password must contain an uppercase letter"})
+	}
+	if policy.RequireLower && !hasLower {
+		violations = append(violations, PasswordViolation{"missing_lowercase", "Password must contain a lowercase letter"})
+	}
+	if policy.RequireDigit && !hasDigit {
+		violations = append(violations, PasswordViolation{"missing_digit", "Password must contain a digit"})
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		violations = append(violations, PasswordViolation{"missing_symbol", "Password must contain a symbol"})
+	}
+
+	lowered := strings.ToLower(password)
+	if len(username) >= 3 && strings.Contains(lowered, strings.ToLower(username)) {
+		violations = append(violations, PasswordViolation{"contains_username", "Password must not contain the username"})
+	}
+	if local, _, _ := strings.Cut(strings.ToLower(email), "@"); len(local) >= 3 && strings.Contains(lowered, local) {
+		violations = append(violations, PasswordViolation{"contains_email", "Password must not contain the email address"})
+	}
+
+	if isBreachedPassword(password) {
+		violations = append(violations, PasswordViolation{"breached", "Password appears in a list of breached passwords"})
+	}
+
+	if userID != 0 {
+		reused, err := passwordReused(userID, password, policy.HistorySize)
+		if err != nil {
+			return nil, err
+		}
+		if reused {
+			violations = append(violations, PasswordViolation{"reused", fmt.Sprintf("Password must not be one of the last %d passwords", policy.HistorySize)})
+		}
+	}
+
+	return violations, nil
+}
+
+// passwordReused checks the current password of the user and the password history
+func passwordReused(userID uint, password string, historySize int) (bool, error) {
+	var user models.User
+	if err := models.DB.Select("id", "password").First(&user, userID).Error; err != nil {
+		return false, err
+	}
+	if user.ComparePassword(password) {
+		return true, nil
+	}
+	return models.PasswordUsedRecently(userID, password, historySize)
+}
@@ -23,6 +23,16 @@ func CreatedResponse(c *gin.Context, data interface{}) {
     })
 }
 
+// ErrorResponseWithDetails mengirim respons error dengan pesan dan daftar detail kesalahan,
+// misalnya aturan password yang dilanggar
+func ErrorResponseWithDetails(c *gin.Context, statusCode int, message string, details interface{}) {
+    c.JSON(statusCode, gin.H{
+        "status":  "error",
+        "message": message,
+        "errors":  details,
+    })
+}
+
 // ErrorResponse mengirim respons error dengan pesan
 func ErrorResponse(c *gin.Context, statusCode int, message string) {
     c.JSON(statusCode, gin.H{
@@ -30,3 +40,12 @@ func ErrorResponse(c *gin.Context, statusCode int, message string) {
         "message": message,
     })
 }
+
+// ForbiddenResponse mengirim respons 403 yang seragam ketika pengguna tidak memiliki izin yang dibutuhkan
+func ForbiddenResponse(c *gin.Context, permission string) {
+    c.JSON(http.StatusForbidden, gin.H{
+        "status":     "error",
+        "message":    "You do not have permission to perform this action",
+        "permission": permission,
+    })
+}
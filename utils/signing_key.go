@@ -0,0 +1,151 @@
+// utils/signing_key.go
+package utils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Algoritma penandatanganan access token yang didukung
+const (
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+// generateSigningKey membuat pasangan kunci baru untuk algoritma yang diminta
+func generateSigningKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case SigningAlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case SigningAlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+// signingMethod mengembalikan metode jwt untuk algoritma yang diminta
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case SigningAlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case SigningAlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+// encodePublicKey mengubah kunci publik menjadi PEM (PKIX)
+func encodePublicKey(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// encryptPrivateKey menyandikan kunci privat (PKCS#8) lalu mengenkripsinya dengan AES-GCM
+// menggunakan kunci turunan dari secret, agar kunci tidak tersimpan dalam bentuk asli di database
+func encryptPrivateKey(private crypto.Signer, secret string) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := keyEncryptionCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, der, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptPrivateKey adalah kebalikan dari encryptPrivateKey
+func decryptPrivateKey(encoded, secret string) (crypto.Signer, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := keyEncryptionCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("encrypted private key is too short")
+	}
+
+	der, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key (was JWT_SECRET changed?): %w", err)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+	return signer, nil
+}
+
+// keyEncryptionCipher membuat AES-256-GCM dengan kunci SHA-256 dari secret
+func keyEncryptionCipher(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET is required to protect signing keys")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// publicJWK mengubah kunci publik menjadi JSON Web Key (RFC 7517 / RFC 8037)
+func publicJWK(kid, algorithm string, public crypto.PublicKey) (map[string]string, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": algorithm,
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"use": "sig",
+			"alg": algorithm,
+			"kid": kid,
+			"x":   base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+}
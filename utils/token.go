@@ -3,6 +3,7 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 )
 
@@ -15,3 +16,9 @@ func GenerateRandomToken(n int) (string, error) {
     }
     return hex.EncodeToString(b), nil
 }
+
+// HashToken menghasilkan digest SHA-256 (hex) dari token, digunakan agar token tidak disimpan dalam bentuk asli
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
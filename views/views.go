@@ -0,0 +1,146 @@
+// views/views.go
+package views
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/mfuadfakhruzzaki/backendaurauran/config"
+	"golang.org/x/text/language"
+)
+
+// Pages rendered for the links sent by email
+const (
+	PageFailure             = "failure"
+	PageEmailVerified       = "email_verified"
+	PageEmailChanged        = "email_changed"
+	PageAccountUnlocked     = "account_unlocked"
+	PagePasswordReset       = "password_reset"
+	PageResetPasswordForm   = "reset_password_form"
+	PageResetPasswordFailed = "reset_password_failed"
+)
+
+//go:embed pages/*.html
+var pageFiles embed.FS
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// fallbackLanguage is used for messages that are missing from a locale bundle
+const fallbackLanguage = "en"
+
+var (
+	// pages holds one template set per page, each combined with the shared layout
+	pages = parsePages()
+	// locales holds the messages of every page per language
+	locales = parseLocales()
+	// languages lists the available locales in the order given to the matcher
+	languages = []language.Tag{language.English, language.Indonesian}
+	matcher   = language.NewMatcher(languages)
+)
+
+// page is the data every page template receives
+type page struct {
+	Lang  string
+	T     map[string]string
+	Brand config.BrandingConfig
+	Data  interface{}
+}
+
+// parsePages parses every page together with the layout. The templates are embedded,
+// so a parse error is a programming error and panics at startup.
+func parsePages() map[string]*template.Template {
+	files, err := pageFiles.ReadDir("pages")
+	if err != nil {
+		panic(err)
+	}
+
+	parsed := make(map[string]*template.Template)
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".html")
+		if name == "layout" {
+			continue
+		}
+		parsed[name] = template.Must(template.ParseFS(pageFiles, "pages/layout.html", path.Join("pages", file.Name())))
+	}
+	return parsed
+}
+
+// parseLocales reads the message bundles, keyed by language, then page, then message
+func parseLocales() map[string]map[string]map[string]string {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	parsed := make(map[string]map[string]map[string]string)
+	for _, file := range files {
+		data, err := localeFiles.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var bundle map[string]map[string]string
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			panic(fmt.Sprintf("invalid locale %s: %v", file.Name(), err))
+		}
+		parsed[strings.TrimSuffix(file.Name(), ".json")] = bundle
+	}
+	return parsed
+}
+
+// Language picks the best available language for an Accept-Language header
+func Language(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return defaultLanguage()
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return defaultLanguage()
+	}
+	base, _ := languages[index].Base()
+	return base.String()
+}
+
+// defaultLanguage returns the configured default language if a bundle exists for it
+func defaultLanguage() string {
+	if _, ok := locales[config.AppConfig.Branding.DefaultLanguage]; ok {
+		return config.AppConfig.Branding.DefaultLanguage
+	}
+	return fallbackLanguage
+}
+
+// messages returns the messages of a page in the given language, with missing ones taken from the fallback language
+func messages(lang, name string) map[string]string {
+	merged := make(map[string]string)
+	for _, source := range []string{"common", name} {
+		for key, value := range locales[fallbackLanguage][source] {
+			merged[key] = value
+		}
+		for key, value := range locales[lang][source] {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// Render writes the page in the given language. data is available to the template as .Data.
+func Render(w io.Writer, name, lang string, data interface{}) error {
+	tmpl, ok := pages[name]
+	if !ok {
+		return fmt.Errorf("unknown page %q", name)
+	}
+
+	return tmpl.ExecuteTemplate(w, "layout", page{
+		Lang:  lang,
+		T:     messages(lang, name),
+		Brand: config.AppConfig.Branding,
+		Data:  data,
+	})
+}